@@ -13,6 +13,7 @@ type InfraTaskInput struct {
 	Key       string `json:"key"`
 	Value     string `json:"value"`
 	Sensitive bool   `json:"sensitive,omitempty"`
+	Type      string `json:"type,omitempty"`
 }
 
 type InfraTaskOutputItem struct {
@@ -31,15 +32,72 @@ type Terraform struct {
 	CredentialsRef Ref    `json:"credentialsRef,omitempty"`
 }
 
+type InfraTaskForEachItem struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+type InfraTaskRetry struct {
+	MaxAttempts   int      `json:"maxAttempts,omitempty"`
+	Backoff       string   `json:"backoff,omitempty"`
+	MaxBackoff    string   `json:"maxBackoff,omitempty"`
+	ErrorPatterns []string `json:"errorPatterns,omitempty"`
+}
+
+// InfraTaskHook runs a command or a shell script in the runner, the inputs and
+// outputs of the task are available as INPUT_<KEY> and OUTPUT_<KEY> variables.
+type InfraTaskHook struct {
+	Name    string   `json:"name"`
+	Command []string `json:"command,omitempty"`
+	Script  string   `json:"script,omitempty"`
+	Timeout string   `json:"timeout,omitempty"`
+}
+
+type InfraTaskHooks struct {
+	PreApply    []InfraTaskHook `json:"preApply,omitempty"`
+	PostApply   []InfraTaskHook `json:"postApply,omitempty"`
+	PreDestroy  []InfraTaskHook `json:"preDestroy,omitempty"`
+	PostDestroy []InfraTaskHook `json:"postDestroy,omitempty"`
+}
+
 type InfraTask struct {
-	Name        string                `json:"name"`
-	Depends     []string              `json:"depends,omitempty"`
-	Backend     string                `json:"backend"`
-	Terraform   Terraform             `json:"terraform,omitempty"`
-	Resource    string                `json:"resource,omitempty"`
-	Inputs      []InfraTaskInput      `json:"inputs"`
-	TaskOutputs []InfraTaskOutput     `json:"taskOutputs,omitempty"`
-	Outputs     []InfraTaskOutputItem `json:"outputs,omitempty"`
+	Name        string                 `json:"name"`
+	Depends     []string               `json:"depends,omitempty"`
+	Backend     string                 `json:"backend"`
+	Terraform   Terraform              `json:"terraform,omitempty"`
+	Resource    string                 `json:"resource,omitempty"`
+	Inputs      []InfraTaskInput       `json:"inputs"`
+	TaskOutputs []InfraTaskOutput      `json:"taskOutputs,omitempty"`
+	Outputs     []InfraTaskOutputItem  `json:"outputs,omitempty"`
+	Timeout     string                 `json:"timeout,omitempty"`
+	Retry       InfraTaskRetry         `json:"retry,omitempty"`
+	When        string                 `json:"when,omitempty"`
+	ForEach     []InfraTaskForEachItem `json:"forEach,omitempty"`
+	// RequiresApproval pauses the apply of the task until it is approved. The
+	// wait counts against the executionTimeout of the infra (10m by default),
+	// tasks not approved in time fail with TASK_APPROVAL_TIME_LIMIT_EXCEEDED
+	RequiresApproval bool           `json:"requiresApproval,omitempty"`
+	Hooks            InfraTaskHooks `json:"hooks,omitempty"`
+	// DeletionPolicy Retain releases the resources of the task instead of
+	// destroying them, Delete is the default
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+type InfraVariableKeyRef struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type InfraVariableValueFrom struct {
+	SecretKeyRef    *InfraVariableKeyRef `json:"secretKeyRef,omitempty"`
+	ConfigMapKeyRef *InfraVariableKeyRef `json:"configMapKeyRef,omitempty"`
+}
+
+type InfraVariable struct {
+	Key       string                  `json:"key"`
+	Value     string                  `json:"value,omitempty"`
+	Sensitive bool                    `json:"sensitive,omitempty"`
+	ValueFrom *InfraVariableValueFrom `json:"valueFrom,omitempty"`
 }
 
 type InfraRunnerConfig struct {
@@ -59,6 +117,10 @@ type InfraSpec struct {
 	ProviderConfigRef Ref               `json:"providerConfigRef,omitempty"`
 	RunnerConfig      InfraRunnerConfig `json:"runnerConfig,omitempty"`
 	Tasks             []InfraTask       `json:"tasks"`
+	ExecutionTimeout  string            `json:"executionTimeout,omitempty"`
+	FailurePolicy     string            `json:"failurePolicy,omitempty"`
+	MaxParallelTasks  int               `json:"maxParallelTasks,omitempty"`
+	Variables         []InfraVariable   `json:"variables,omitempty"`
 }
 
 type TaskStatus struct {
@@ -66,6 +128,15 @@ type TaskStatus struct {
 	Resource       string `json:"resource,omitempty"`
 	DependencyLock string `json:"dependencyLock,omitempty"`
 	State          string `json:"state,omitempty"`
+	Fingerprint    string `json:"fingerprint,omitempty"`
+	// SourceRevision is the revision of the source applied, e.g. the commit
+	// of a git source or the digest of an oci artifact
+	SourceRevision string `json:"sourceRevision,omitempty"`
+	// Hooks and DeletionPolicy are kept to destroy tasks removed from the spec
+	Hooks          InfraTaskHooks `json:"hooks,omitempty"`
+	DeletionPolicy string         `json:"deletionPolicy,omitempty"`
+	// ArchivedState is the secret keeping the state of a retained task
+	ArchivedState *Ref `json:"archivedState,omitempty"`
 }
 
 type Error struct {
@@ -74,17 +145,70 @@ type Error struct {
 	Tip     string `json:"tip,omitempty"`
 }
 
+type TaskPlanStatus struct {
+	Add      int    `json:"add,omitempty"`
+	Change   int    `json:"change,omitempty"`
+	Destroy  int    `json:"destroy,omitempty"`
+	Rendered string `json:"rendered,omitempty"`
+}
+
+type TaskAttemptStatus struct {
+	Attempt    int    `json:"attempt"`
+	StartedAt  string `json:"startedAt,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+	Error      Error  `json:"error,omitempty"`
+}
+
+type TaskApprovalPlan struct {
+	Name  string         `json:"name"`
+	Plan  TaskPlanStatus `json:"plan,omitempty"`
+	Error Error          `json:"error,omitempty"`
+}
+
+// TaskApprovalStatus records who approved a task and the plans of the task and
+// its dependents shown while it waited for the approval.
+type TaskApprovalStatus struct {
+	Approver   string             `json:"approver,omitempty"`
+	ApprovedAt string             `json:"approvedAt,omitempty"`
+	Plans      []TaskApprovalPlan `json:"plans,omitempty"`
+}
+
+type TaskHookStatus struct {
+	Name       string `json:"name"`
+	Phase      string `json:"phase"`
+	StartedAt  string `json:"startedAt,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+	ExitCode   int    `json:"exitCode"`
+	Output     string `json:"output,omitempty"`
+	Error      Error  `json:"error,omitempty"`
+}
+
+type TaskExecutionOutput struct {
+	Key string `json:"key"`
+	// Value is empty for sensitive outputs, they are only kept in the state
+	Value     string `json:"value"`
+	Type      string `json:"type,omitempty"`
+	Sensitive bool   `json:"sensitive,omitempty"`
+	// Unknown outputs of a plan are only known after the apply
+	Unknown bool `json:"unknown,omitempty"`
+}
+
 type TaskExecutionStatus struct {
-	Name        string            `json:"name"`
-	Depends     []string          `json:"depends,omitempty"`
-	Backend     string            `json:"backend"`
-	Inputs      []InfraTaskInput  `json:"inputs"`
-	Task        TaskStatus        `json:"task"`
-	TaskOutputs []InfraTaskOutput `json:"taskOutputs,omitempty"`
-	StartedAt   string            `json:"startedAt,omitempty"`
-	FinishedAt  string            `json:"finishedAt,omitempty"`
-	Status      string            `json:"status,omitempty"`
-	Error       Error             `json:"error,omitempty"`
+	Name        string                `json:"name"`
+	Depends     []string              `json:"depends,omitempty"`
+	Backend     string                `json:"backend"`
+	Inputs      []InfraTaskInput      `json:"inputs"`
+	Task        TaskStatus            `json:"task"`
+	TaskOutputs []InfraTaskOutput     `json:"taskOutputs,omitempty"`
+	StartedAt   string                `json:"startedAt,omitempty"`
+	FinishedAt  string                `json:"finishedAt,omitempty"`
+	Status      string                `json:"status,omitempty"`
+	Error       Error                 `json:"error,omitempty"`
+	Plan        TaskPlanStatus        `json:"plan,omitempty"`
+	Attempts    []TaskAttemptStatus   `json:"attempts,omitempty"`
+	Outputs     []TaskExecutionOutput `json:"outputs,omitempty"`
+	Approval    *TaskApprovalStatus   `json:"approval,omitempty"`
+	Hooks       []TaskHookStatus      `json:"hooks,omitempty"`
 }
 
 type ExecutionStatus struct {
@@ -95,8 +219,21 @@ type ExecutionStatus struct {
 	Error      Error                 `json:"error,omitempty"`
 }
 
+// TaskApproval is an approval given to a task of the running execution.
+type TaskApproval struct {
+	Task string `json:"task"`
+	// Approver is the user authenticated by the proxy in front of the API
+	// server, approvals without an authenticated user are rejected
+	Approver   string `json:"approver"`
+	ApprovedAt string `json:"approvedAt"`
+}
+
 type InfraStatus struct {
 	LastExecution ExecutionStatus `json:"lastExecution,omitempty"`
+	LastPlan      ExecutionStatus `json:"lastPlan,omitempty"`
+	// Approvals are given to the tasks waiting for approval, they are cleared
+	// when a new execution starts
+	Approvals []TaskApproval `json:"approvals,omitempty"`
 }
 
 //+kubebuilder:object:root=true
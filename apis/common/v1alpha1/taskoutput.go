@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -9,10 +10,13 @@ type TaskOutputSpecItemSecret struct {
 	Namespace string `json:"namespace,omitempty"`
 }
 
+// TaskOutputSpecItem keeps the terraform output as JSON, Type is the terraform
+// type of the output, e.g. "string" or ["list","string"].
 type TaskOutputSpecItem struct {
-	Key       string `json:"key,omitempty"`
-	Value     string `json:"value,omitempty"`
-	Sensitive bool   `json:"sensitive,omitempty"`
+	Key       string                `json:"key,omitempty"`
+	Value     *apiextensionsv1.JSON `json:"value,omitempty"`
+	Type      *apiextensionsv1.JSON `json:"type,omitempty"`
+	Sensitive bool                  `json:"sensitive,omitempty"`
 }
 
 type TaskOutputSpec struct {
@@ -22,6 +22,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -164,6 +165,13 @@ func (in *InfraSpec) DeepCopyInto(out *InfraSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make([]InfraVariable, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraSpec.
@@ -180,6 +188,12 @@ func (in *InfraSpec) DeepCopy() *InfraSpec {
 func (in *InfraStatus) DeepCopyInto(out *InfraStatus) {
 	*out = *in
 	in.LastExecution.DeepCopyInto(&out.LastExecution)
+	in.LastPlan.DeepCopyInto(&out.LastPlan)
+	if in.Approvals != nil {
+		in, out := &in.Approvals, &out.Approvals
+		*out = make([]TaskApproval, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraStatus.
@@ -218,6 +232,13 @@ func (in *InfraTask) DeepCopyInto(out *InfraTask) {
 		*out = make([]InfraTaskOutputItem, len(*in))
 		copy(*out, *in)
 	}
+	in.Retry.DeepCopyInto(&out.Retry)
+	if in.ForEach != nil {
+		in, out := &in.ForEach, &out.ForEach
+		*out = make([]InfraTaskForEachItem, len(*in))
+		copy(*out, *in)
+	}
+	in.Hooks.DeepCopyInto(&out.Hooks)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraTask.
@@ -230,6 +251,84 @@ func (in *InfraTask) DeepCopy() *InfraTask {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfraTaskForEachItem) DeepCopyInto(out *InfraTaskForEachItem) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraTaskForEachItem.
+func (in *InfraTaskForEachItem) DeepCopy() *InfraTaskForEachItem {
+	if in == nil {
+		return nil
+	}
+	out := new(InfraTaskForEachItem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfraTaskHook) DeepCopyInto(out *InfraTaskHook) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraTaskHook.
+func (in *InfraTaskHook) DeepCopy() *InfraTaskHook {
+	if in == nil {
+		return nil
+	}
+	out := new(InfraTaskHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfraTaskHooks) DeepCopyInto(out *InfraTaskHooks) {
+	*out = *in
+	if in.PreApply != nil {
+		in, out := &in.PreApply, &out.PreApply
+		*out = make([]InfraTaskHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostApply != nil {
+		in, out := &in.PostApply, &out.PostApply
+		*out = make([]InfraTaskHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PreDestroy != nil {
+		in, out := &in.PreDestroy, &out.PreDestroy
+		*out = make([]InfraTaskHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostDestroy != nil {
+		in, out := &in.PostDestroy, &out.PostDestroy
+		*out = make([]InfraTaskHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraTaskHooks.
+func (in *InfraTaskHooks) DeepCopy() *InfraTaskHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(InfraTaskHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InfraTaskInput) DeepCopyInto(out *InfraTaskInput) {
 	*out = *in
@@ -295,6 +394,86 @@ func (in *InfraTaskRef) DeepCopy() *InfraTaskRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfraTaskRetry) DeepCopyInto(out *InfraTaskRetry) {
+	*out = *in
+	if in.ErrorPatterns != nil {
+		in, out := &in.ErrorPatterns, &out.ErrorPatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraTaskRetry.
+func (in *InfraTaskRetry) DeepCopy() *InfraTaskRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(InfraTaskRetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfraVariable) DeepCopyInto(out *InfraVariable) {
+	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(InfraVariableValueFrom)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraVariable.
+func (in *InfraVariable) DeepCopy() *InfraVariable {
+	if in == nil {
+		return nil
+	}
+	out := new(InfraVariable)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfraVariableKeyRef) DeepCopyInto(out *InfraVariableKeyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraVariableKeyRef.
+func (in *InfraVariableKeyRef) DeepCopy() *InfraVariableKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(InfraVariableKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfraVariableValueFrom) DeepCopyInto(out *InfraVariableValueFrom) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(InfraVariableKeyRef)
+		**out = **in
+	}
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(InfraVariableKeyRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfraVariableValueFrom.
+func (in *InfraVariableValueFrom) DeepCopy() *InfraVariableValueFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(InfraVariableValueFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProviderConfig) DeepCopyInto(out *ProviderConfig) {
 	*out = *in
@@ -508,6 +687,89 @@ func (in *RepositorySync) DeepCopy() *RepositorySync {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskApproval) DeepCopyInto(out *TaskApproval) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskApproval.
+func (in *TaskApproval) DeepCopy() *TaskApproval {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskApproval)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskApprovalPlan) DeepCopyInto(out *TaskApprovalPlan) {
+	*out = *in
+	out.Plan = in.Plan
+	out.Error = in.Error
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskApprovalPlan.
+func (in *TaskApprovalPlan) DeepCopy() *TaskApprovalPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskApprovalPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskApprovalStatus) DeepCopyInto(out *TaskApprovalStatus) {
+	*out = *in
+	if in.Plans != nil {
+		in, out := &in.Plans, &out.Plans
+		*out = make([]TaskApprovalPlan, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskApprovalStatus.
+func (in *TaskApprovalStatus) DeepCopy() *TaskApprovalStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskApprovalStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskAttemptStatus) DeepCopyInto(out *TaskAttemptStatus) {
+	*out = *in
+	out.Error = in.Error
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskAttemptStatus.
+func (in *TaskAttemptStatus) DeepCopy() *TaskAttemptStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskAttemptStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskExecutionOutput) DeepCopyInto(out *TaskExecutionOutput) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskExecutionOutput.
+func (in *TaskExecutionOutput) DeepCopy() *TaskExecutionOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskExecutionOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TaskExecutionStatus) DeepCopyInto(out *TaskExecutionStatus) {
 	*out = *in
@@ -521,7 +783,7 @@ func (in *TaskExecutionStatus) DeepCopyInto(out *TaskExecutionStatus) {
 		*out = make([]InfraTaskInput, len(*in))
 		copy(*out, *in)
 	}
-	out.Task = in.Task
+	in.Task.DeepCopyInto(&out.Task)
 	if in.TaskOutputs != nil {
 		in, out := &in.TaskOutputs, &out.TaskOutputs
 		*out = make([]InfraTaskOutput, len(*in))
@@ -530,6 +792,27 @@ func (in *TaskExecutionStatus) DeepCopyInto(out *TaskExecutionStatus) {
 		}
 	}
 	out.Error = in.Error
+	out.Plan = in.Plan
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]TaskAttemptStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make([]TaskExecutionOutput, len(*in))
+		copy(*out, *in)
+	}
+	if in.Approval != nil {
+		in, out := &in.Approval, &out.Approval
+		*out = new(TaskApprovalStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = make([]TaskHookStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskExecutionStatus.
@@ -542,6 +825,22 @@ func (in *TaskExecutionStatus) DeepCopy() *TaskExecutionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskHookStatus) DeepCopyInto(out *TaskHookStatus) {
+	*out = *in
+	out.Error = in.Error
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskHookStatus.
+func (in *TaskHookStatus) DeepCopy() *TaskHookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskHookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TaskOutput) DeepCopyInto(out *TaskOutput) {
 	*out = *in
@@ -608,7 +907,9 @@ func (in *TaskOutputSpec) DeepCopyInto(out *TaskOutputSpec) {
 	if in.Outputs != nil {
 		in, out := &in.Outputs, &out.Outputs
 		*out = make([]TaskOutputSpecItem, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	out.Secret = in.Secret
 }
@@ -626,6 +927,16 @@ func (in *TaskOutputSpec) DeepCopy() *TaskOutputSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TaskOutputSpecItem) DeepCopyInto(out *TaskOutputSpecItem) {
 	*out = *in
+	if in.Value != nil {
+		in, out := &in.Value, &out.Value
+		*out = new(v1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Type != nil {
+		in, out := &in.Type, &out.Type
+		*out = new(v1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskOutputSpecItem.
@@ -668,10 +979,31 @@ func (in *TaskOutputStatus) DeepCopy() *TaskOutputStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskPlanStatus) DeepCopyInto(out *TaskPlanStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskPlanStatus.
+func (in *TaskPlanStatus) DeepCopy() *TaskPlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskPlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TaskStatus) DeepCopyInto(out *TaskStatus) {
 	*out = *in
 	out.Terraform = in.Terraform
+	in.Hooks.DeepCopyInto(&out.Hooks)
+	if in.ArchivedState != nil {
+		in, out := &in.ArchivedState, &out.ArchivedState
+		*out = new(Ref)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskStatus.
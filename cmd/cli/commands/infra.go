@@ -1,11 +1,18 @@
 package commands
 
 import (
-	"github.com/octopipe/cloudx/internal/taskmanager"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-resty/resty/v2"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 type infraCmd struct {
+	logger     *zap.Logger
+	restclient *resty.Client
 }
 
 func (p infraCmd) NewInfraCmd() *cobra.Command {
@@ -28,11 +35,55 @@ func (p infraCmd) NewCreateInfraCmd() *cobra.Command {
 	}
 }
 
-func NewInfraRoot(taskManager taskmanager.Manager) *cobra.Command {
-	infraRoot := infraCmd{}
+func (p infraCmd) NewApproveInfraCmd() *cobra.Command {
+	var namespace, task string
+	approveCmd := &cobra.Command{
+		Use:   "approve [infra]",
+		Short: "approve a task waiting for approval",
+		Long:  "approve a task waiting for approval as the user authenticated by the proxy in front of the API server, APISERVER_TOKEN is sent as the bearer token",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			req := p.restclient.R()
+			if token := os.Getenv("APISERVER_TOKEN"); token != "" {
+				req.SetAuthToken(token)
+			}
+
+			res, err := req.
+				SetQueryParams(map[string]string{
+					"namespace": namespace,
+					"task":      task,
+				}).
+				Patch(fmt.Sprintf("%s/infra/%s/approve", os.Getenv("APISERVER_BASE_PATH"), args[0]))
+			if err != nil {
+				fmt.Println(err.Error())
+				return
+			}
+
+			if res.StatusCode() != http.StatusNoContent {
+				fmt.Println(res.String())
+				return
+			}
+
+			fmt.Printf("task %s of infra %s approved\n", task, args[0])
+		},
+	}
+
+	approveCmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "namespace of the infra")
+	approveCmd.Flags().StringVar(&task, "task", "", "name of the task waiting for approval")
+	approveCmd.MarkFlagRequired("task")
+
+	return approveCmd
+}
+
+func NewInfraRoot(logger *zap.Logger, restclient *resty.Client) *cobra.Command {
+	infraRoot := infraCmd{
+		logger:     logger,
+		restclient: restclient,
+	}
 
 	infraCmd := infraRoot.NewInfraCmd()
 	infraCmd.AddCommand(infraRoot.NewCreateInfraCmd())
+	infraCmd.AddCommand(infraRoot.NewApproveInfraCmd())
 
 	return infraCmd
 }
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/octopipe/cloudx/internal/taskmanager"
 	"github.com/spf13/cobra"
@@ -30,8 +31,21 @@ func (p taskCmd) NewPublishTaskCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
 			contents := map[string][]byte{}
-			filepath.Walk(args[1], func(path string, info fs.FileInfo, err error) error {
+			err := filepath.Walk(args[1], func(path string, info fs.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
 				if info.IsDir() {
+					if info.Name() == ".terraform" {
+						return filepath.SkipDir
+					}
+
+					return nil
+				}
+
+				// states of local runs aren't part of the task
+				if strings.HasPrefix(info.Name(), "terraform.tfstate") {
 					return nil
 				}
 
@@ -40,12 +54,21 @@ func (p taskCmd) NewPublishTaskCmd() *cobra.Command {
 					return err
 				}
 
-				contents[info.Name()] = file
+				// files are kept in the same directories of the module
+				rel, err := filepath.Rel(args[1], path)
+				if err != nil {
+					return err
+				}
+
+				contents[filepath.ToSlash(rel)] = file
 
 				return nil
 			})
+			if err != nil {
+				log.Fatalln(err)
+			}
 
-			err := p.taskManager.Publish(args[0], contents)
+			err = p.taskManager.Publish(args[0], contents)
 			if err != nil {
 				log.Fatalln(err)
 			}
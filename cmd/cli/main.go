@@ -14,10 +14,11 @@ func main() {
 	restclient := resty.New()
 	// taskManager := taskmanager.NewTaskManager(logger)
 	// taskCmd := commands.NewTaskRoot(taskManager)
-	// infraCmd := commands.NewInfraRoot(taskManager)
+	infraCmd := commands.NewInfraRoot(logger, restclient)
 	repositoryCmd := commands.NewRepositoryRoot(logger, restclient)
 
 	commands.RootCmd.AddCommand(repositoryCmd)
+	commands.RootCmd.AddCommand(infraCmd)
 
 	if err := commands.RootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -7,10 +7,13 @@ import (
 
 	"github.com/joho/godotenv"
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/approval"
 	"github.com/octopipe/cloudx/internal/controller/infra"
 	"github.com/octopipe/cloudx/internal/controller/runner"
 	"github.com/octopipe/cloudx/internal/provider"
+	"github.com/octopipe/cloudx/internal/statearchive"
 	"github.com/octopipe/cloudx/internal/taskoutput"
+	"github.com/octopipe/cloudx/internal/variable"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -83,6 +86,9 @@ func main() {
 	taskOutputRPCServer := taskoutput.NewTaskOutputRPCHandler(logger, mgr.GetClient(), taskOutputRepository)
 	rpc.Register(infraRPCServer)
 	rpc.Register(taskOutputRPCServer)
+	rpc.Register(variable.NewVariableRPCHandler(logger, mgr.GetClient()))
+	rpc.Register(approval.NewApprovalRPCHandler(logger, mgr.GetClient()))
+	rpc.Register(statearchive.NewStateArchiveRPCHandler(logger, mgr.GetClient()))
 	rpc.HandleHTTP()
 	l, e := net.Listen("tcp", ":9000")
 	if e != nil {
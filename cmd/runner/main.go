@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -48,7 +51,7 @@ func main() {
 		logger:    logger,
 	}
 
-	infraRef, action := newRunnerContext.getDataFromCommandArgs()
+	infraRef, action, options, err := newRunnerContext.getDataFromCommandArgs()
 	if err != nil {
 		panic(err)
 	}
@@ -69,15 +72,23 @@ func main() {
 	}
 
 	newBackend := backend.NewBackend(terraformBackend)
-	newPipeline := pipeline.NewPipeline(logger, rpcClient, newBackend)
+	maxParallelTasks := pipeline.DefaultMaxParallelTasks
+	if rawMaxParallelTasks := os.Getenv("MAX_PARALLEL_TASKS"); rawMaxParallelTasks != "" {
+		maxParallelTasks, err = strconv.Atoi(rawMaxParallelTasks)
+		if err != nil {
+			logger.Fatal("Invalid max parallel tasks", zap.Error(err), zap.String("value", rawMaxParallelTasks))
+		}
+	}
+
+	newPipeline := pipeline.NewPipeline(logger, rpcClient, newBackend, maxParallelTasks)
 
 	go func() {
 		logger.Info("start pipeline execution")
-		newPipeline.Start(action, *currentInfra, statusChan)
+		newPipeline.Start(context.Background(), action, *currentInfra, options, statusChan)
 	}()
 
 	for executionStatus := range statusChan {
-		err = newRunnerContext.setExecutionStatus(infraRef, executionStatus)
+		err = newRunnerContext.setExecutionStatus(action, infraRef, executionStatus)
 		if err != nil {
 			logger.Fatal("Failed to call rpc execution status", zap.Error(err))
 		}
@@ -89,15 +100,20 @@ func main() {
 	}
 }
 
-func (c runnerContext) setExecutionStatus(infraRef types.NamespacedName, executionStatus commonv1alpha1.ExecutionStatus) error {
+func (c runnerContext) setExecutionStatus(action string, infraRef types.NamespacedName, executionStatus commonv1alpha1.ExecutionStatus) error {
 	c.logger.Info("New status received calling controller...")
 	rpcRunnerFinishedArgs := &infra.RPCSetExecutionStatusArgs{
 		Ref:             infraRef,
 		ExecutionStatus: executionStatus,
 	}
 
+	method := "RPCServer.SetExecutionStatus"
+	if action == pipeline.PlanAction {
+		method = "RPCServer.SetPlanStatus"
+	}
+
 	var reply int
-	err := c.rpcClient.Call("RPCServer.SetExecutionStatus", rpcRunnerFinishedArgs, &reply)
+	err := c.rpcClient.Call(method, rpcRunnerFinishedArgs, &reply)
 	if err != nil {
 		return err
 	}
@@ -105,11 +121,24 @@ func (c runnerContext) setExecutionStatus(infraRef types.NamespacedName, executi
 	return nil
 }
 
-func (c runnerContext) getDataFromCommandArgs() (types.NamespacedName, string) {
+func (c runnerContext) getDataFromCommandArgs() (types.NamespacedName, string, pipeline.ExecutionOptions, error) {
 	commandArgs := os.Args[1:]
 	action := commandArgs[0]
 	rawInfraRef := commandArgs[1]
 
+	options := pipeline.ExecutionOptions{}
+	flags := flag.NewFlagSet("runner", flag.ContinueOnError)
+	flags.StringVar(&options.ReconcileMode, "reconcile-mode", "", "force, drift-check or resume the apply of the tasks")
+	targets := flags.String("targets", "", "comma separated list of tasks to execute")
+	err := flags.Parse(commandArgs[2:])
+	if err != nil {
+		return types.NamespacedName{}, "", pipeline.ExecutionOptions{}, err
+	}
+
+	if *targets != "" {
+		options.Targets = strings.Split(*targets, ",")
+	}
+
 	infraRef := types.NamespacedName{}
 
 	s := strings.Split(rawInfraRef, "/")
@@ -121,5 +150,5 @@ func (c runnerContext) getDataFromCommandArgs() (types.NamespacedName, string) {
 		infraRef.Namespace = "default"
 	}
 
-	return infraRef, action
+	return infraRef, action, options, nil
 }
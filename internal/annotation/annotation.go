@@ -1,7 +1,27 @@
 package annotation
 
 const (
-	ManagedByAnnotation = "octopipe.io/managed-by"
+	ManagedByAnnotation     = "octopipe.io/managed-by"
+	ActionAnnotation        = "commons.cloudx.io/action"
+	ReconcileModeAnnotation = "commons.cloudx.io/reconcile-mode"
+	TargetsAnnotation       = "commons.cloudx.io/targets"
+	// AllowedNamespacesAnnotation lists the namespaces, comma separated or *,
+	// whose infras can read a task output of another namespace. It isn't a
+	// label because label values can't hold a list of namespaces or *.
+	// The requester namespace is the namespace of the infra named by the
+	// runner in the RPC call, the RPC server doesn't authenticate the runner,
+	// so any pod that reaches the controller RPC port can name an infra of an
+	// allowed namespace. Restrict the access to the port with a network policy
+	// when the outputs must not be read outside the allowed namespaces.
+	AllowedNamespacesAnnotation = "commons.cloudx.io/allowed-namespaces"
+	// SourceAnnotation is the terraform source and version of an archived state
+	SourceAnnotation = "commons.cloudx.io/source"
+)
+
+// InfraLabel and TaskLabel identify the task of the archived terraform states
+const (
+	InfraLabel = "commons.cloudx.io/infra"
+	TaskLabel  = "commons.cloudx.io/task"
 )
 
 var DefaultAnnotations = map[string]string{
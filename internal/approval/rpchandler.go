@@ -0,0 +1,48 @@
+package approval
+
+import (
+	"context"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ApprovalRPCHandler struct {
+	logger    *zap.Logger
+	k8sClient client.Client
+}
+
+func NewApprovalRPCHandler(logger *zap.Logger, k8sClient client.Client) *ApprovalRPCHandler {
+	return &ApprovalRPCHandler{
+		logger:    logger,
+		k8sClient: k8sClient,
+	}
+}
+
+type RPCGetTaskApprovalArgs struct {
+	Ref  types.NamespacedName
+	Task string
+}
+
+// GetTaskApproval replies the approval given to a task of the running
+// execution, the approver is empty while the task is not approved.
+func (h *ApprovalRPCHandler) GetTaskApproval(args *RPCGetTaskApprovalArgs, reply *commonv1alpha1.TaskApproval) error {
+	h.logger.Info("received call", zap.String("method", "ApprovalRPCHandler.GetTaskApproval"), zap.String("infra", args.Ref.String()), zap.String("task", args.Task))
+	currentInfra := commonv1alpha1.Infra{}
+	err := h.k8sClient.Get(context.Background(), args.Ref, &currentInfra)
+	if err != nil {
+		return err
+	}
+
+	*reply = commonv1alpha1.TaskApproval{Task: args.Task}
+	for _, a := range currentInfra.Status.Approvals {
+		if a.Task == args.Task {
+			*reply = a
+			break
+		}
+	}
+
+	return nil
+}
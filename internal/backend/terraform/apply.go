@@ -3,6 +3,7 @@ package terraform
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -44,93 +45,92 @@ func persistPreviousState(previousState string, workdirPath string) error {
 	return nil
 }
 
-func (t terraformBackend) Apply(input TerraformApplyInput) (TerraformApplyResult, error) {
-	t.logger.Info("get terrafrom from source", zap.String("source", input.Source))
-
-	workdirPath, err := t.dowloadSource(input.Source)
-	if err != nil {
-		return TerraformApplyResult{}, err
-	}
+type terraformState struct {
+	Outputs map[string]tfexec.OutputMeta `json:"outputs"`
+}
 
-	t.logger.Info("install terraform by version", zap.String("version", input.Version))
-	terraformPath, err := t.install(input.Version)
+// GetStateOutputs reads the outputs of a persisted state without running terraform.
+func GetStateOutputs(state string) (map[string]tfexec.OutputMeta, error) {
+	rawState, err := base64.StdEncoding.DecodeString(strings.Trim(state, "\""))
 	if err != nil {
-		return TerraformApplyResult{}, err
+		return nil, err
 	}
 
-	execVarsFilePath := filepath.Join(workdirPath, "exec.tfvars")
-	f, err := os.Create(execVarsFilePath)
+	parsedState := terraformState{}
+	err = json.Unmarshal(rawState, &parsedState)
 	if err != nil {
-		return TerraformApplyResult{}, err
+		return nil, err
 	}
 
-	t.logger.Info("creating terraform vars file", zap.String("workdir", workdirPath))
-	for _, i := range input.TaskInputs {
-		f.WriteString(fmt.Sprintf("%s = \"%s\"\n", i.Key, i.Value))
-	}
+	return parsedState.Outputs, nil
+}
 
-	tf, err := tfexec.NewTerraform(workdirPath, terraformPath)
+func (t terraformBackend) Apply(ctx context.Context, input TerraformApplyInput) (TerraformApplyResult, error) {
+	ws, err := t.prepareWorkspace(ctx, workspaceInput{
+		Source:           input.Source,
+		Version:          input.Version,
+		TaskInputs:       input.TaskInputs,
+		PreviousState:    input.PreviousState,
+		PreviousLockDeps: input.PreviousLockDeps,
+		Credentials:      input.Credentials,
+		Revision:         input.Revision,
+	})
 	if err != nil {
 		return TerraformApplyResult{}, err
 	}
 
-	if input.PreviousLockDeps != "" {
-		err = persistDependenciesLock(input.PreviousLockDeps, workdirPath)
-		if err != nil {
-			return TerraformApplyResult{}, err
-		}
-	}
-
-	t.logger.Info("executing terraform init", zap.String("workdir", workdirPath), zap.String("tfpath", terraformPath))
-	err = tf.Init(context.TODO())
+	t.logger.Info("executing terraform plan", zap.String("workdir", ws.workdirPath))
+	hasModifications, err := ws.tf.Plan(ctx, tfexec.VarFile(ws.varsFilePath))
 	if err != nil {
-		t.logger.Error(err.Error())
 		return TerraformApplyResult{}, err
 	}
 
-	if input.PreviousState != "" {
-		err := persistPreviousState(input.PreviousState, workdirPath)
+	if hasModifications {
+		t.logger.Info("executing terraform apply", zap.String("workdir", ws.workdirPath))
+		err = ws.tf.Apply(ctx, tfexec.VarFile(ws.varsFilePath))
 		if err != nil {
-			return TerraformApplyResult{}, err
+			// terraform persists the resources created until the failure, they must
+			// be kept to be tracked by the next executions
+			state, lockDeps, _ := readStateFiles(ws.workdirPath)
+			return TerraformApplyResult{
+				State:            state,
+				DependenciesLock: lockDeps,
+				SourceRevision:   ws.sourceRevision,
+			}, err
 		}
 	}
 
-	t.logger.Info("executing terraform plan", zap.String("workdir", workdirPath))
-	hasModifications, err := tf.Plan(context.Background(), tfexec.VarFile(execVarsFilePath))
+	out, err := ws.tf.Output(ctx)
 	if err != nil {
 		return TerraformApplyResult{}, err
 	}
 
-	if hasModifications {
-		t.logger.Info("executing terraform apply", zap.String("workdir", workdirPath))
-		err = tf.Apply(context.Background(), tfexec.VarFile(execVarsFilePath))
-		if err != nil {
-			return TerraformApplyResult{}, err
-		}
-	}
-
-	out, err := tf.Output(context.Background())
+	t.logger.Info("get terraform state file and lock deps", zap.String("workdir", ws.workdirPath))
+	state, lockDeps, err := readStateFiles(ws.workdirPath)
 	if err != nil {
 		return TerraformApplyResult{}, err
 	}
 
-	t.logger.Info("get terraform state file", zap.String("workdir", workdirPath))
+	return TerraformApplyResult{
+		Outputs:          out,
+		State:            state,
+		DependenciesLock: lockDeps,
+		SourceRevision:   ws.sourceRevision,
+	}, nil
+}
+
+func readStateFiles(workdirPath string) (string, string, error) {
 	stateFilePath := fmt.Sprintf("%s/terraform.tfstate", workdirPath)
 	stateFile, err := os.ReadFile(stateFilePath)
 	if err != nil {
-		return TerraformApplyResult{}, err
+		return "", "", err
 	}
 
-	t.logger.Info("get terraform lock deps", zap.String("workdir", workdirPath))
 	lockDepsFilePath := fmt.Sprintf("%s/.terraform.lock.hcl", workdirPath)
 	lockDepsFile, err := os.ReadFile(lockDepsFilePath)
 	if err != nil {
-		return TerraformApplyResult{}, err
+		return "", "", err
 	}
 
-	return TerraformApplyResult{
-		Outputs:          out,
-		State:            base64.StdEncoding.EncodeToString(stateFile),
-		DependenciesLock: base64.StdEncoding.EncodeToString(lockDepsFile),
-	}, nil
+	return base64.StdEncoding.EncodeToString(stateFile), base64.StdEncoding.EncodeToString(lockDepsFile), nil
 }
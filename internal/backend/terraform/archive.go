@@ -0,0 +1,176 @@
+package terraform
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxArchiveSize is the maximum size of a downloaded source archive, larger
+// downloads fail before the runner memory is exhausted.
+var maxArchiveSize int64 = 100 << 20
+
+// readArchive reads the content of a downloaded archive up to maxArchiveSize.
+func readArchive(r io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(r, maxArchiveSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(content)) > maxArchiveSize {
+		return nil, fmt.Errorf("the archive exceeds the maximum size of %d bytes", maxArchiveSize)
+	}
+
+	return content, nil
+}
+
+// verifyChecksum checks the content of a downloaded source against a checksum
+// in the sha256:<hex> format, an empty checksum is not verified.
+func verifyChecksum(content []byte, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+
+	algorithm, expected, ok := strings.Cut(checksum, ":")
+	if !ok || algorithm != "sha256" {
+		return fmt.Errorf("invalid checksum %s, use sha256:<hex>", checksum)
+	}
+
+	sum := sha256.Sum256(content)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch, expected %s and got %s", expected, actual)
+	}
+
+	return nil
+}
+
+// extractArchive writes the files of a .tar.gz, .tgz, .tar or .zip archive in
+// the workdir keeping its directory structure.
+func extractArchive(name string, content []byte, workdir string) error {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gr, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+
+		return extractTar(tar.NewReader(gr), workdir)
+	case strings.HasSuffix(name, ".tar"):
+		return extractTar(tar.NewReader(bytes.NewReader(content)), workdir)
+	case strings.HasSuffix(name, ".zip"):
+		return extractZip(content, workdir)
+	default:
+		return fmt.Errorf("unsupported archive %s, use a .tar.gz, .tgz, .tar or .zip file", name)
+	}
+}
+
+func extractTar(tr *tar.Reader, workdir string) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			path, err := getArchiveEntryPath(workdir, hdr.Name)
+			if err != nil {
+				return err
+			}
+
+			err = os.MkdirAll(path, os.ModePerm)
+			if err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			err = writeArchiveEntry(workdir, hdr.Name, tr)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(content []byte, workdir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			path, err := getArchiveEntryPath(workdir, f.Name)
+			if err != nil {
+				return err
+			}
+
+			err = os.MkdirAll(path, os.ModePerm)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+
+		err = writeArchiveEntry(workdir, f.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeArchiveEntry(workdir string, name string, r io.Reader) error {
+	path, err := getArchiveEntryPath(workdir, name)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
+
+// getArchiveEntryPath returns the path of an archive entry in the workdir,
+// entries escaping the workdir (e.g. ../main.tf) are rejected.
+func getArchiveEntryPath(workdir string, name string) (string, error) {
+	path := filepath.Join(workdir, name)
+	rel, err := filepath.Rel(workdir, path)
+	if err != nil || filepath.IsAbs(name) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("invalid archive entry %s", name)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,123 @@
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// fileDownload copies a local module directory to a new workdir, so the
+// terraform files created by the execution don't touch the module. The
+// revision is the hash of the module content.
+func (t terraformBackend) fileDownload(dir string) (string, string, error) {
+	t.logger.Info("copying local task module", zap.String("dir", dir))
+	revision, err := resolveFileSource(dir)
+	if err != nil {
+		return "", "", err
+	}
+
+	workdir := fmt.Sprintf("/tmp/cloudx/executions/%s", uuid.New().String())
+	err = walkModuleFiles(dir, func(path string, rel string, d fs.DirEntry) error {
+		target := filepath.Join(workdir, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+
+		return copyFile(path, target)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return workdir, revision, nil
+}
+
+// resolveFileSource hashes the paths and the content of the module files, so
+// edits of a local module are applied by the next execution. File sources are
+// only allowed on local environments, runners would read their own filesystem.
+func resolveFileSource(dir string) (string, error) {
+	if os.Getenv("ENV") != "local" {
+		return "", fmt.Errorf("file sources are only allowed when ENV=local, use a git, s3, https or oci source")
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		return "", fmt.Errorf("the file source %s must be a directory", dir)
+	}
+
+	hash := sha256.New()
+	err = walkModuleFiles(dir, func(path string, rel string, d fs.DirEntry) error {
+		if d.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fmt.Fprintf(hash, "%s\n", filepath.ToSlash(rel))
+		_, err = io.Copy(hash, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// walkModuleFiles walks the directories and regular files of a module, the
+// terraform data and states of local runs are skipped.
+func walkModuleFiles(dir string, fn func(path string, rel string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() && d.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+
+		if !d.IsDir() && (!d.Type().IsRegular() || strings.HasPrefix(d.Name(), "terraform.tfstate")) {
+			return nil
+		}
+
+		return fn(path, rel, d)
+	})
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
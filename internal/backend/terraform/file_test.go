@@ -0,0 +1,59 @@
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "files"), os.ModePerm))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".terraform", "providers"), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("# sns"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "files", "policy.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte("{}"), 0644))
+
+	backend, _ := NewTerraformBackend(zap.NewNop())
+	ctx := context.Background()
+
+	t.Setenv("ENV", "")
+	_, _, err := backend.dowloadSource(ctx, "file://"+dir, nil, "")
+	assert.EqualError(t, err, "file sources are only allowed when ENV=local, use a git, s3, https or oci source")
+
+	_, err = backend.ResolveSource(ctx, "file://"+dir, nil)
+	assert.Error(t, err)
+
+	t.Setenv("ENV", "local")
+	workdir, revision, err := backend.dowloadSource(ctx, "file://"+dir, nil, "")
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(workdir, "files", "policy.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(content))
+
+	for _, skipped := range []string{".terraform", "terraform.tfstate"} {
+		_, err = os.Stat(filepath.Join(workdir, skipped))
+		assert.True(t, os.IsNotExist(err), skipped)
+	}
+
+	resolved, err := backend.ResolveSource(ctx, "file://"+dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, revision, resolved)
+
+	// local runs don't change the revision, module edits do
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte(`{"version": 4}`), 0644))
+	resolved, err = backend.ResolveSource(ctx, "file://"+dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, revision, resolved)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("# sqs"), 0644))
+	resolved, err = backend.ResolveSource(ctx, "file://"+dir, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, revision, resolved)
+}
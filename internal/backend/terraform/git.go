@@ -0,0 +1,249 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/google/uuid"
+	"github.com/octopipe/cloudx/internal/gitauth"
+	"go.uber.org/zap"
+)
+
+var (
+	commitHashRegex      = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	shortCommitHashRegex = regexp.MustCompile(`^[0-9a-f]{4,39}$`)
+	scpLikeUrlRegex      = regexp.MustCompile(`^[^/@:]+@[^/:]+:`)
+)
+
+type gitSource struct {
+	Url    string
+	Subdir string
+	Ref    string
+}
+
+// parseGitSource splits a git source in the repository url, the module
+// subdirectory and the ref, e.g. host/org/repo.git//modules/sns?ref=v1.2.0.
+// Sources without scheme are cloned through https and sources starting with
+// a slash are local repositories.
+func parseGitSource(sourceUrl string) (gitSource, error) {
+	source := gitSource{}
+	if i := strings.LastIndex(sourceUrl, "?"); i >= 0 {
+		query, err := url.ParseQuery(sourceUrl[i+1:])
+		if err != nil {
+			return gitSource{}, fmt.Errorf("invalid git source query: %w", err)
+		}
+
+		source.Ref = query.Get("ref")
+		sourceUrl = sourceUrl[:i]
+	}
+
+	schemeLength := 0
+	if i := strings.Index(sourceUrl, "://"); i >= 0 {
+		schemeLength = i + len("://")
+	}
+
+	if i := strings.Index(sourceUrl[schemeLength:], "//"); i >= 0 {
+		source.Subdir = strings.Trim(sourceUrl[schemeLength+i+len("//"):], "/")
+		sourceUrl = sourceUrl[:schemeLength+i]
+	}
+
+	if sourceUrl == "" {
+		return gitSource{}, fmt.Errorf("invalid git source, the repository url is empty")
+	}
+
+	source.Url = sourceUrl
+	if schemeLength == 0 && !strings.HasPrefix(sourceUrl, "/") && !scpLikeUrlRegex.MatchString(sourceUrl) {
+		source.Url = "https://" + sourceUrl
+	}
+
+	return source, nil
+}
+
+// gitDownload clones the repository and checks out the ref of the source, or
+// the revision when the execution is pinned to a commit. It returns the module
+// directory and the commit checked out. Only the checked out files are moved to
+// the workdir, the clone is removed.
+func (t terraformBackend) gitDownload(ctx context.Context, sourceUrl string, credentials map[string][]byte, revision string) (string, string, error) {
+	source, err := parseGitSource(sourceUrl)
+	if err != nil {
+		return "", "", err
+	}
+
+	dir := fmt.Sprintf("/tmp/cloudx/sources/%s", uuid.New().String())
+	defer os.RemoveAll(dir)
+
+	t.logger.Info("cloning task repository", zap.String("url", source.Url), zap.String("ref", source.Ref), zap.String("revision", revision))
+	repo, err := git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+		URL:  source.Url,
+		Auth: gitauth.NewAuthMethod(credentials),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to clone %s: %w", source.Url, err)
+	}
+
+	ref := source.Ref
+	if revision != "" {
+		ref = revision
+	}
+
+	hash, err := resolveGitRevision(repo, ref)
+	if err != nil {
+		return "", "", err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = worktree.Checkout(&git.CheckoutOptions{Hash: hash, Force: true})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to checkout %s: %w", hash.String(), err)
+	}
+
+	moduleDir := filepath.Join(dir, source.Subdir)
+	rel, err := filepath.Rel(dir, moduleDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", "", fmt.Errorf("invalid git source subdirectory %s", source.Subdir)
+	}
+
+	info, err := os.Stat(moduleDir)
+	if err != nil || !info.IsDir() {
+		return "", "", fmt.Errorf("not found subdirectory %s in %s", source.Subdir, source.Url)
+	}
+
+	// the whole checkout is kept, modules can reference other directories of
+	// the repository
+	err = os.RemoveAll(filepath.Join(dir, git.GitDirName))
+	if err != nil {
+		return "", "", err
+	}
+
+	workdir := fmt.Sprintf("/tmp/cloudx/executions/%s", uuid.New().String())
+	err = os.MkdirAll(filepath.Dir(workdir), os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = os.Rename(dir, workdir)
+	if err != nil {
+		return "", "", err
+	}
+
+	return filepath.Join(workdir, rel), hash.String(), nil
+}
+
+// resolveGitRevision resolves a branch, tag or commit of a cloned repository,
+// an empty ref is the default branch.
+func resolveGitRevision(repo *git.Repository, ref string) (plumbing.Hash, error) {
+	if ref == "" {
+		head, err := repo.Head()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		return head.Hash(), nil
+	}
+
+	// only the default branch is created locally by the clone
+	for _, rev := range []string{ref, "origin/" + ref} {
+		hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+		if err == nil {
+			return *hash, nil
+		}
+	}
+
+	return plumbing.ZeroHash, fmt.Errorf("not found ref %s in repository", ref)
+}
+
+// resolveGitSource lists the refs of the remote repository to resolve the ref
+// of the source to a commit without cloning it.
+func resolveGitSource(ctx context.Context, sourceUrl string, credentials map[string][]byte) (string, error) {
+	source, err := parseGitSource(sourceUrl)
+	if err != nil {
+		return "", err
+	}
+
+	if commitHashRegex.MatchString(source.Ref) {
+		return source.Ref, nil
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{source.Url},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{
+		Auth:          gitauth.NewAuthMethod(credentials),
+		PeelingOption: git.AppendPeeled,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list refs of %s: %w", source.Url, err)
+	}
+
+	hashes := map[plumbing.ReferenceName]plumbing.Hash{}
+	targets := map[plumbing.ReferenceName]plumbing.ReferenceName{}
+	for _, r := range refs {
+		if r.Type() == plumbing.SymbolicReference {
+			targets[r.Name()] = r.Target()
+			continue
+		}
+
+		hashes[r.Name()] = r.Hash()
+	}
+
+	candidates := []plumbing.ReferenceName{plumbing.HEAD}
+	if source.Ref != "" {
+		// annotated tags are peeled to the commit they point to
+		candidates = []plumbing.ReferenceName{
+			plumbing.NewBranchReferenceName(source.Ref),
+			plumbing.ReferenceName(plumbing.NewTagReferenceName(source.Ref).String() + "^{}"),
+			plumbing.NewTagReferenceName(source.Ref),
+		}
+	}
+
+	for _, name := range candidates {
+		if target, ok := targets[name]; ok {
+			name = target
+		}
+
+		if hash, ok := hashes[name]; ok {
+			return hash.String(), nil
+		}
+	}
+
+	// short commit hashes aren't listed by the remote, they are resolved in a
+	// clone of the repository
+	if shortCommitHashRegex.MatchString(source.Ref) {
+		return resolveGitCommitPrefix(ctx, source, credentials)
+	}
+
+	return "", fmt.Errorf("not found ref %s in %s", source.Ref, source.Url)
+}
+
+// resolveGitCommitPrefix clones the repository in memory to resolve a short
+// commit hash to the full hash.
+func resolveGitCommitPrefix(ctx context.Context, source gitSource, credentials map[string][]byte) (string, error) {
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
+		URL:  source.Url,
+		Auth: gitauth.NewAuthMethod(credentials),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w", source.Url, err)
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(source.Ref))
+	if err != nil {
+		return "", fmt.Errorf("not found ref %s in %s", source.Ref, source.Url)
+	}
+
+	return hash.String(), nil
+}
@@ -0,0 +1,158 @@
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestGitRepository creates a bare repository with a module tagged as v1.2.0
+// and a newer commit in the default branch.
+func newTestGitRepository(t *testing.T) (string, string, string) {
+	workdir := t.TempDir()
+	repo, err := git.PlainInit(workdir, false)
+	require.NoError(t, err)
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commit := func(content string) plumbing.Hash {
+		require.NoError(t, os.MkdirAll(filepath.Join(workdir, "modules", "sns"), os.ModePerm))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "modules", "sns", "main.tf"), []byte(content), 0644))
+		_, err := worktree.Add("modules")
+		require.NoError(t, err)
+
+		hash, err := worktree.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "cloudx", Email: "cloudx@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+		return hash
+	}
+
+	tagged := commit("# v1.2.0")
+	_, err = repo.CreateTag("v1.2.0", tagged, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "cloudx", Email: "cloudx@example.com", When: time.Now()},
+		Message: "v1.2.0",
+	})
+	require.NoError(t, err)
+	latest := commit("# latest")
+
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	_, err = git.PlainClone(bare, true, &git.CloneOptions{URL: workdir})
+	require.NoError(t, err)
+
+	return bare, tagged.String(), latest.String()
+}
+
+func TestParseGitSource(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected gitSource
+	}{
+		{"github.com/org/repo.git//modules/sns?ref=v1.2.0", gitSource{Url: "https://github.com/org/repo.git", Subdir: "modules/sns", Ref: "v1.2.0"}},
+		{"https://github.com/org/repo.git", gitSource{Url: "https://github.com/org/repo.git"}},
+		{"ssh://git@github.com/org/repo.git//sns", gitSource{Url: "ssh://git@github.com/org/repo.git", Subdir: "sns"}},
+		{"git@github.com:org/repo.git?ref=main", gitSource{Url: "git@github.com:org/repo.git", Ref: "main"}},
+		{"/srv/repo.git//modules/sns/", gitSource{Url: "/srv/repo.git", Subdir: "modules/sns"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			source, err := parseGitSource(tt.source)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, source)
+		})
+	}
+}
+
+func TestGitSource(t *testing.T) {
+	bare, tagged, latest := newTestGitRepository(t)
+	backend, _ := NewTerraformBackend(zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("download tag and subdirectory", func(t *testing.T) {
+		workdir, revision, err := backend.dowloadSource(ctx, "git://"+bare+"//modules/sns?ref=v1.2.0", nil, "")
+		assert.NoError(t, err)
+		assert.Equal(t, tagged, revision)
+
+		content, err := os.ReadFile(filepath.Join(workdir, "main.tf"))
+		assert.NoError(t, err)
+		assert.Equal(t, "# v1.2.0", string(content))
+	})
+
+	t.Run("download default branch", func(t *testing.T) {
+		_, revision, err := backend.dowloadSource(ctx, "git://"+bare+"//modules/sns", nil, "")
+		assert.NoError(t, err)
+		assert.Equal(t, latest, revision)
+	})
+
+	t.Run("download pinned revision", func(t *testing.T) {
+		workdir, revision, err := backend.dowloadSource(ctx, "git://"+bare+"//modules/sns?ref=master", nil, tagged)
+		assert.NoError(t, err)
+		assert.Equal(t, tagged, revision)
+
+		content, err := os.ReadFile(filepath.Join(workdir, "main.tf"))
+		assert.NoError(t, err)
+		assert.Equal(t, "# v1.2.0", string(content))
+	})
+
+	t.Run("reject subdirectory outside the repository", func(t *testing.T) {
+		_, _, err := backend.dowloadSource(ctx, "git://"+bare+"//../outside", nil, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("resolve refs", func(t *testing.T) {
+		revision, err := backend.ResolveSource(ctx, "git://"+bare+"//modules/sns?ref=v1.2.0", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, tagged, revision)
+
+		revision, err = backend.ResolveSource(ctx, "git://"+bare+"//modules/sns?ref=master", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, latest, revision)
+
+		revision, err = backend.ResolveSource(ctx, "git://"+bare, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, latest, revision)
+
+		_, err = backend.ResolveSource(ctx, "git://"+bare+"?ref=unknown", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("resolve short commit hash", func(t *testing.T) {
+		revision, err := backend.ResolveSource(ctx, "git://"+bare+"//modules/sns?ref="+tagged[:7], nil)
+		assert.NoError(t, err)
+		assert.Equal(t, tagged, revision)
+
+		workdir, revision, err := backend.dowloadSource(ctx, "git://"+bare+"//modules/sns?ref="+tagged[:7], nil, "")
+		assert.NoError(t, err)
+		assert.Equal(t, tagged, revision)
+
+		content, err := os.ReadFile(filepath.Join(workdir, "main.tf"))
+		assert.NoError(t, err)
+		assert.Equal(t, "# v1.2.0", string(content))
+
+		_, err = backend.ResolveSource(ctx, "git://"+bare+"?ref=0000000", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("remove the clone", func(t *testing.T) {
+		sources, _ := os.ReadDir("/tmp/cloudx/sources")
+		workdir, _, err := backend.dowloadSource(ctx, "git://"+bare+"//modules/sns", nil, "")
+		assert.NoError(t, err)
+
+		remaining, _ := os.ReadDir("/tmp/cloudx/sources")
+		assert.Equal(t, len(sources), len(remaining))
+
+		_, err = os.Stat(filepath.Join(workdir, "..", "..", git.GitDirName))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
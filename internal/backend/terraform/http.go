@@ -0,0 +1,107 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// httpSourceClient downloads the archives of https sources.
+var httpSourceClient = http.DefaultClient
+
+// parseHttpSource removes the checksum from the query of an archive url, the
+// other query params are kept (e.g. the signature of a presigned url).
+func parseHttpSource(sourceUrl string) (string, string, error) {
+	u, err := url.Parse("https://" + sourceUrl)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid https source: %w", err)
+	}
+
+	query := u.Query()
+	checksum := query.Get("checksum")
+	if checksum == "" {
+		return "", "", fmt.Errorf("https sources require a checksum, use https://host/module.tar.gz?checksum=sha256:<hex>")
+	}
+
+	query.Del("checksum")
+	u.RawQuery = query.Encode()
+	return u.String(), checksum, nil
+}
+
+// stripCredentialsOnRedirect removes the credentials of the source from
+// redirects to another host, e.g. a mirror redirecting to a storage bucket.
+// The http client only strips them for other domains, not for other ports.
+func stripCredentialsOnRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	if req.URL.Host != via[0].URL.Host {
+		req.Header.Del("Authorization")
+	}
+
+	return nil
+}
+
+// httpDownload extracts the archive of the source in a new workdir after
+// verifying its checksum, the checksum is the revision of the source.
+func (t terraformBackend) httpDownload(ctx context.Context, sourceUrl string, credentials map[string][]byte) (string, string, error) {
+	archiveUrl, checksum, err := parseHttpSource(sourceUrl)
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveUrl, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	if token := string(credentials["token"]); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if len(credentials["username"]) > 0 {
+		req.SetBasicAuth(string(credentials["username"]), string(credentials["password"]))
+	}
+
+	t.logger.Info("downloading task archive", zap.String("url", req.URL.Redacted()))
+	client := *httpSourceClient
+	client.CheckRedirect = stripCredentialsOnRedirect
+	res, err := client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to download %s: %s", req.URL.Redacted(), res.Status)
+	}
+
+	content, err := readArchive(res.Body)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = verifyChecksum(content, checksum)
+	if err != nil {
+		return "", "", err
+	}
+
+	workdir := fmt.Sprintf("/tmp/cloudx/executions/%s", uuid.New().String())
+	err = os.MkdirAll(workdir, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = extractArchive(path.Base(req.URL.Path), content, workdir)
+	if err != nil {
+		return "", "", err
+	}
+
+	return workdir, checksum, nil
+}
@@ -0,0 +1,121 @@
+package terraform
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestHttpSource(t *testing.T) {
+	content := newTestZip(t, map[string]string{"sns/main.tf": "# sns"})
+	sum := sha256.Sum256(content)
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		if r.URL.Path != "/modules/sns.zip" || r.URL.Query().Get("checksum") != "" || username != "mirror" || password != "secret" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	defaultClient := httpSourceClient
+	httpSourceClient = server.Client()
+	defer func() { httpSourceClient = defaultClient }()
+
+	backend, _ := NewTerraformBackend(zap.NewNop())
+	ctx := context.Background()
+	host := strings.TrimPrefix(server.URL, "https://")
+	credentials := map[string][]byte{"username": []byte("mirror"), "password": []byte("secret")}
+
+	t.Run("download archive", func(t *testing.T) {
+		source := "https://" + host + "/modules/sns.zip?checksum=" + checksum
+		workdir, revision, err := backend.dowloadSource(ctx, source, credentials, "")
+		assert.NoError(t, err)
+		assert.Equal(t, checksum, revision)
+
+		file, err := os.ReadFile(filepath.Join(workdir, "sns", "main.tf"))
+		assert.NoError(t, err)
+		assert.Equal(t, "# sns", string(file))
+
+		resolved, err := backend.ResolveSource(ctx, source, credentials)
+		assert.NoError(t, err)
+		assert.Equal(t, checksum, resolved)
+	})
+
+	t.Run("require checksum", func(t *testing.T) {
+		_, _, err := backend.dowloadSource(ctx, "https://"+host+"/modules/sns.zip", credentials, "")
+		assert.ErrorContains(t, err, "require a checksum")
+	})
+
+	t.Run("reject archive larger than the maximum size", func(t *testing.T) {
+		defaultMaxArchiveSize := maxArchiveSize
+		maxArchiveSize = int64(len(content) - 1)
+		t.Cleanup(func() { maxArchiveSize = defaultMaxArchiveSize })
+
+		source := "https://" + host + "/modules/sns.zip?checksum=" + checksum
+		_, _, err := backend.dowloadSource(ctx, source, credentials, "")
+		assert.ErrorContains(t, err, "the archive exceeds the maximum size")
+	})
+
+	t.Run("strip credentials on redirect to another host", func(t *testing.T) {
+		storage := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			w.Write(content)
+		}))
+		defer storage.Close()
+
+		mirror := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, _, ok := r.BasicAuth(); !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			http.Redirect(w, r, storage.URL+"/sns.zip", http.StatusFound)
+		}))
+		defer mirror.Close()
+
+		source := "https://" + strings.TrimPrefix(mirror.URL, "https://") + "/modules/sns.zip?checksum=" + checksum
+		_, revision, err := backend.dowloadSource(ctx, source, credentials, "")
+		assert.NoError(t, err)
+		assert.Equal(t, checksum, revision)
+	})
+
+	t.Run("reject checksum mismatch", func(t *testing.T) {
+		_, _, err := backend.dowloadSource(ctx, "https://"+host+"/modules/sns.zip?checksum=sha256:0000", credentials, "")
+		assert.ErrorContains(t, err, "checksum mismatch")
+	})
+}
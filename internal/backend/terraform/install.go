@@ -10,7 +10,7 @@ import (
 	"github.com/hashicorp/hc-install/releases"
 )
 
-func (t terraformBackend) install(tfVersion string) (string, error) {
+func (t terraformBackend) install(ctx context.Context, tfVersion string) (string, error) {
 	if tfVersion != "" {
 		installDirPath := filepath.Join("/tmp/cloudx/terraform-versions", tfVersion)
 		if _, err := os.Stat(filepath.Join(installDirPath, "terraform")); os.IsNotExist(err) {
@@ -26,7 +26,7 @@ func (t terraformBackend) install(tfVersion string) (string, error) {
 			}
 
 			t.logger.Info("install terraform by specific version")
-			res, err := installer.Install(context.Background())
+			res, err := installer.Install(ctx)
 			return res, err
 		}
 
@@ -48,7 +48,7 @@ func (t terraformBackend) install(tfVersion string) (string, error) {
 		}
 
 		t.logger.Info("install terraform by latest version")
-		res, err := installer.Install(context.Background())
+		res, err := installer.Install(ctx)
 		return res, err
 	}
 
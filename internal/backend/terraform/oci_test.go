@@ -0,0 +1,70 @@
+package terraform
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/octopipe/cloudx/internal/taskartifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func pushTestTask(t *testing.T, ref string, files map[string][]byte) {
+	img, err := taskartifact.NewImage(files)
+	require.NoError(t, err)
+	require.NoError(t, crane.Push(img, ref))
+}
+
+func TestOCISource(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+
+	ref := strings.TrimPrefix(server.URL, "http://") + "/task:sns"
+	backend, _ := NewTerraformBackend(zap.NewNop())
+	ctx := context.Background()
+
+	pushTestTask(t, ref, map[string][]byte{"main.tf": []byte("# v1"), "modules/topic/main.tf": []byte("# topic")})
+	digest, err := backend.ResolveSource(ctx, "oci://"+ref, nil)
+	require.NoError(t, err)
+	pushTestTask(t, ref, map[string][]byte{"main.tf": []byte("# v2")})
+
+	t.Run("download nested directories pinned to digest", func(t *testing.T) {
+		workdir, revision, err := backend.dowloadSource(ctx, "oci://"+ref, nil, digest)
+		assert.NoError(t, err)
+		assert.Equal(t, digest, revision)
+
+		content, err := os.ReadFile(filepath.Join(workdir, "modules", "topic", "main.tf"))
+		assert.NoError(t, err)
+		assert.Equal(t, "# topic", string(content))
+	})
+
+	t.Run("download tag", func(t *testing.T) {
+		workdir, revision, err := backend.dowloadSource(ctx, "oci://"+ref, nil, "")
+		assert.NoError(t, err)
+		assert.NotEqual(t, digest, revision)
+
+		content, err := os.ReadFile(filepath.Join(workdir, "main.tf"))
+		assert.NoError(t, err)
+		assert.Equal(t, "# v2", string(content))
+	})
+
+	t.Run("reject path traversal", func(t *testing.T) {
+		_, err := taskartifact.NewImage(map[string][]byte{"../main.tf": []byte("# outside")})
+		assert.Error(t, err)
+
+		// images published without the task media types are extracted as well
+		img, err := crane.Image(map[string][]byte{"../main.tf": []byte("# outside")})
+		require.NoError(t, err)
+		require.NoError(t, crane.Push(img, ref))
+
+		_, _, err = backend.dowloadSource(ctx, "oci://"+ref, nil, "")
+		assert.Error(t, err)
+	})
+}
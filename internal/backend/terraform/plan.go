@@ -0,0 +1,108 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+	"go.uber.org/zap"
+)
+
+func (t terraformBackend) Plan(ctx context.Context, input TerraformPlanInput) (TerraformPlanResult, error) {
+	ws, err := t.prepareWorkspace(ctx, workspaceInput{
+		Source:           input.Source,
+		Version:          input.Version,
+		TaskInputs:       input.TaskInputs,
+		PreviousState:    input.PreviousState,
+		PreviousLockDeps: input.PreviousLockDeps,
+		Credentials:      input.Credentials,
+		Revision:         input.Revision,
+	})
+	if err != nil {
+		return TerraformPlanResult{}, err
+	}
+
+	planFilePath := filepath.Join(ws.workdirPath, "exec.tfplan")
+	t.logger.Info("executing terraform plan", zap.String("workdir", ws.workdirPath), zap.Bool("destroy", input.Destroy))
+	_, err = ws.tf.Plan(ctx, tfexec.VarFile(ws.varsFilePath), tfexec.Out(planFilePath), tfexec.Destroy(input.Destroy))
+	if err != nil {
+		return TerraformPlanResult{}, err
+	}
+
+	plan, err := ws.tf.ShowPlanFile(ctx, planFilePath)
+	if err != nil {
+		return TerraformPlanResult{}, err
+	}
+
+	rendered, err := ws.tf.ShowPlanFileRaw(ctx, planFilePath)
+	if err != nil {
+		return TerraformPlanResult{}, err
+	}
+
+	result := TerraformPlanResult{
+		Rendered: rendered,
+	}
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil {
+			continue
+		}
+
+		switch {
+		case rc.Change.Actions.Replace():
+			result.Add++
+			result.Destroy++
+		case rc.Change.Actions.Create():
+			result.Add++
+		case rc.Change.Actions.Update():
+			result.Change++
+		case rc.Change.Actions.Delete():
+			result.Destroy++
+		}
+	}
+
+	result.Outputs, err = getPlannedOutputs(plan)
+	if err != nil {
+		return TerraformPlanResult{}, err
+	}
+
+	return result, nil
+}
+
+// getPlannedOutputs returns the outputs as they will be after the apply,
+// outputs that are only known after the apply are marked as unknown.
+func getPlannedOutputs(plan *tfjson.Plan) (map[string]PlannedOutput, error) {
+	outputs := map[string]PlannedOutput{}
+	if plan.PlannedValues != nil {
+		for key, out := range plan.PlannedValues.Outputs {
+			value, err := json.Marshal(out.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			outputs[key] = PlannedOutput{
+				OutputMeta: tfexec.OutputMeta{
+					Sensitive: out.Sensitive,
+					Value:     value,
+				},
+			}
+		}
+	}
+
+	for key, change := range plan.OutputChanges {
+		if _, ok := outputs[key]; ok || change == nil || change.AfterUnknown != true {
+			continue
+		}
+
+		outputs[key] = PlannedOutput{
+			OutputMeta: tfexec.OutputMeta{
+				Sensitive: change.AfterSensitive == true,
+			},
+			Unknown: true,
+		}
+	}
+
+	return outputs, nil
+}
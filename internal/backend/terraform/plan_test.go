@@ -0,0 +1,41 @@
+package terraform
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPlannedOutputs(t *testing.T) {
+	plan := &tfjson.Plan{
+		PlannedValues: &tfjson.StateValues{
+			Outputs: map[string]*tfjson.StateOutput{
+				"name":     {Value: "vpc"},
+				"password": {Value: "secret", Sensitive: true},
+			},
+		},
+		OutputChanges: map[string]*tfjson.Change{
+			"name":     {AfterUnknown: false},
+			"password": {AfterUnknown: false},
+			"arn":      {AfterUnknown: true},
+			"token":    {AfterUnknown: true, AfterSensitive: true},
+		},
+	}
+
+	outputs, err := getPlannedOutputs(plan)
+	assert.NoError(t, err)
+	assert.Len(t, outputs, 4)
+
+	assert.False(t, outputs["name"].Unknown)
+	assert.Equal(t, json.RawMessage(`"vpc"`), outputs["name"].Value)
+	assert.True(t, outputs["password"].Sensitive)
+
+	assert.True(t, outputs["arn"].Unknown)
+	assert.Nil(t, outputs["arn"].Value)
+	assert.False(t, outputs["arn"].Sensitive)
+
+	assert.True(t, outputs["token"].Unknown)
+	assert.True(t, outputs["token"].Sensitive)
+}
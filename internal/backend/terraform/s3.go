@@ -0,0 +1,180 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const defaultS3Region = "us-east-1"
+
+type s3Source struct {
+	Bucket   string
+	Key      string
+	Endpoint string
+	Region   string
+	Checksum string
+}
+
+// parseS3Source splits a s3 source in the bucket and the key of the module
+// bundle, e.g. bucket/modules/sns.tar.gz?endpoint=http://minio:9000&checksum=sha256:<hex>.
+func parseS3Source(sourceUrl string) (s3Source, error) {
+	source := s3Source{}
+	if i := strings.Index(sourceUrl, "?"); i >= 0 {
+		query, err := url.ParseQuery(sourceUrl[i+1:])
+		if err != nil {
+			return s3Source{}, fmt.Errorf("invalid s3 source query: %w", err)
+		}
+
+		source.Endpoint = query.Get("endpoint")
+		source.Region = query.Get("region")
+		source.Checksum = query.Get("checksum")
+		sourceUrl = sourceUrl[:i]
+	}
+
+	bucket, key, ok := strings.Cut(sourceUrl, "/")
+	if !ok || bucket == "" || key == "" {
+		return s3Source{}, fmt.Errorf("invalid s3 source, use s3://bucket/key")
+	}
+
+	source.Bucket, source.Key = bucket, key
+	return source, nil
+}
+
+// newS3Client uses the credentials of the provider config given to the runner
+// environment, the credentials of the task source take precedence when set.
+// Custom endpoints (e.g. MinIO) are accessed with path style urls.
+func newS3Client(ctx context.Context, source s3Source, creds map[string][]byte) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, func(opts *config.LoadOptions) error {
+		if source.Region != "" {
+			opts.Region = source.Region
+		}
+
+		if len(creds["aws_access_key_id"]) > 0 {
+			opts.Credentials = credentials.NewStaticCredentialsProvider(
+				string(creds["aws_access_key_id"]),
+				string(creds["aws_secret_access_key"]),
+				string(creds["aws_session_token"]),
+			)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = defaultS3Region
+	}
+
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if source.Endpoint != "" {
+			o.EndpointResolver = s3.EndpointResolverFromURL(source.Endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}
+
+// s3Download extracts the module bundle of the source in a new workdir, it
+// returns the version id of the object or its ETag on unversioned buckets.
+// ETags are sent as If-Match, so the download fails when the object was
+// overwritten instead of returning another content.
+func (t terraformBackend) s3Download(ctx context.Context, sourceUrl string, creds map[string][]byte, revision string) (string, string, error) {
+	source, err := parseS3Source(sourceUrl)
+	if err != nil {
+		return "", "", err
+	}
+
+	client, err := newS3Client(ctx, source, creds)
+	if err != nil {
+		return "", "", err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(source.Bucket),
+		Key:    aws.String(source.Key),
+	}
+	if isETag(revision) {
+		input.IfMatch = aws.String(revision)
+	} else if revision != "" {
+		input.VersionId = aws.String(revision)
+	}
+
+	t.logger.Info("downloading task bundle", zap.String("bucket", source.Bucket), zap.String("key", source.Key), zap.String("revision", revision))
+	object, err := client.GetObject(ctx, input)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get s3://%s/%s: %w", source.Bucket, source.Key, err)
+	}
+	defer object.Body.Close()
+
+	content, err := readArchive(object.Body)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = verifyChecksum(content, source.Checksum)
+	if err != nil {
+		return "", "", err
+	}
+
+	workdir := fmt.Sprintf("/tmp/cloudx/executions/%s", uuid.New().String())
+	err = os.MkdirAll(workdir, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = extractArchive(path.Base(source.Key), content, workdir)
+	if err != nil {
+		return "", "", err
+	}
+
+	return workdir, getS3ObjectRevision(object.VersionId, object.ETag), nil
+}
+
+// resolveS3Source returns the revision of the current object without
+// downloading it.
+func resolveS3Source(ctx context.Context, sourceUrl string, creds map[string][]byte) (string, error) {
+	source, err := parseS3Source(sourceUrl)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := newS3Client(ctx, source, creds)
+	if err != nil {
+		return "", err
+	}
+
+	object, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(source.Bucket),
+		Key:    aws.String(source.Key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get s3://%s/%s: %w", source.Bucket, source.Key, err)
+	}
+
+	return getS3ObjectRevision(object.VersionId, object.ETag), nil
+}
+
+func getS3ObjectRevision(versionId *string, etag *string) string {
+	if v := aws.ToString(versionId); v != "" && v != "null" {
+		return v
+	}
+
+	return aws.ToString(etag)
+}
+
+// isETag checks if a revision is an ETag, they are always quoted unlike
+// version ids.
+func isETag(revision string) bool {
+	return strings.HasPrefix(revision, "\"")
+}
@@ -0,0 +1,143 @@
+package terraform
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestTarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	return buf.Bytes()
+}
+
+// newTestS3Server serves a single object as a S3-compatible storage with path
+// style urls, the version id is only set when the object is versioned.
+func newTestS3Server(t *testing.T, key string, content []byte, versionId string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/modules/"+key {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if v := r.URL.Query().Get("versionId"); v != "" && v != versionId {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if m := r.Header.Get("If-Match"); m != "" && m != "\"etag-1\"" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		w.Header().Set("ETag", "\"etag-1\"")
+		if versionId != "" {
+			w.Header().Set("x-amz-version-id", versionId)
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestS3Source(t *testing.T) {
+	content := newTestTarGz(t, map[string]string{"main.tf": "# sns", "files/policy.json": "{}"})
+	sum := sha256.Sum256(content)
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+	creds := map[string][]byte{"aws_access_key_id": []byte("id"), "aws_secret_access_key": []byte("secret")}
+	backend, _ := NewTerraformBackend(zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("download bundle", func(t *testing.T) {
+		server := newTestS3Server(t, "sns.tar.gz", content, "")
+		source := "s3://modules/sns.tar.gz?region=us-east-1&endpoint=" + server.URL + "&checksum=" + checksum
+
+		workdir, revision, err := backend.dowloadSource(ctx, source, creds, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "\"etag-1\"", revision)
+
+		file, err := os.ReadFile(filepath.Join(workdir, "files", "policy.json"))
+		assert.NoError(t, err)
+		assert.Equal(t, "{}", string(file))
+
+		resolved, err := backend.ResolveSource(ctx, source, creds)
+		assert.NoError(t, err)
+		assert.Equal(t, "\"etag-1\"", resolved)
+
+		_, revision, err = backend.dowloadSource(ctx, source, creds, "\"etag-1\"")
+		assert.NoError(t, err)
+		assert.Equal(t, "\"etag-1\"", revision)
+
+		// the object was overwritten since the etag was resolved
+		_, _, err = backend.dowloadSource(ctx, source, creds, "\"etag-0\"")
+		assert.Error(t, err)
+	})
+
+	t.Run("download versioned bundle", func(t *testing.T) {
+		server := newTestS3Server(t, "sns.tar.gz", content, "v2")
+		source := "s3://modules/sns.tar.gz?region=us-east-1&endpoint=" + server.URL
+
+		_, revision, err := backend.dowloadSource(ctx, source, creds, "v2")
+		assert.NoError(t, err)
+		assert.Equal(t, "v2", revision)
+
+		_, _, err = backend.dowloadSource(ctx, source, creds, "v1")
+		assert.Error(t, err)
+	})
+
+	t.Run("reject bundle larger than the maximum size", func(t *testing.T) {
+		defaultMaxArchiveSize := maxArchiveSize
+		maxArchiveSize = int64(len(content) - 1)
+		t.Cleanup(func() { maxArchiveSize = defaultMaxArchiveSize })
+
+		server := newTestS3Server(t, "sns.tar.gz", content, "")
+		source := "s3://modules/sns.tar.gz?region=us-east-1&endpoint=" + server.URL
+
+		_, _, err := backend.dowloadSource(ctx, source, creds, "")
+		assert.ErrorContains(t, err, "the archive exceeds the maximum size")
+	})
+
+	t.Run("reject checksum mismatch", func(t *testing.T) {
+		server := newTestS3Server(t, "sns.tar.gz", content, "")
+		source := "s3://modules/sns.tar.gz?region=us-east-1&endpoint=" + server.URL + "&checksum=sha256:0000"
+
+		_, _, err := backend.dowloadSource(ctx, source, creds, "")
+		assert.ErrorContains(t, err, "checksum mismatch")
+	})
+}
+
+func TestExtractArchive(t *testing.T) {
+	workdir := t.TempDir()
+	err := extractArchive("module.tar.gz", newTestTarGz(t, map[string]string{"../main.tf": "# outside"}), workdir)
+	assert.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(workdir), "main.tf"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -2,69 +2,103 @@ package terraform
 
 import (
 	"archive/tar"
-	"bytes"
+	"context"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/uuid"
+	"github.com/octopipe/cloudx/internal/taskartifact"
 	"go.uber.org/zap"
 )
 
-func (p terraformBackend) ociDownload(sourceUrl string) (string, error) {
-	p.logger.Info("pulling task image", zap.String("image", sourceUrl))
-	img, err := crane.Pull(sourceUrl)
+// ociDownload pulls the task artifact, pinned to the digest of the revision
+// when set, and extracts its content layers keeping the directory structure.
+func (t terraformBackend) ociDownload(ctx context.Context, sourceUrl string, revision string) (string, string, error) {
+	ref, err := name.ParseReference(sourceUrl)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	var buf bytes.Buffer
-	err = crane.Export(img, &buf)
+	if revision != "" {
+		ref = ref.Context().Digest(revision)
+	}
+
+	t.logger.Info("pulling task image", zap.String("image", ref.String()))
+	img, err := crane.Pull(ref.String(), crane.WithContext(ctx))
+	if err != nil {
+		return "", "", err
+	}
+
+	digest, err := img.Digest()
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+
+	layers, err := taskartifact.GetContentLayers(img)
+	if err != nil {
+		return "", "", err
 	}
 
-	tr := tar.NewReader(&buf)
-	content := map[string]string{}
 	workdir := fmt.Sprintf("/tmp/cloudx/executions/%s", uuid.New().String())
 	err = os.MkdirAll(workdir, os.ModePerm)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
+	for _, layer := range layers {
+		err = extractLayer(layer, workdir)
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
+	}
 
-		b, err := io.ReadAll(tr)
-		if err != nil {
-			return "", err
-		}
+	return workdir, digest.String(), nil
+}
 
-		content[hdr.Name] = string(b)
-		f, err := os.Create(fmt.Sprintf("%s/%s", workdir, hdr.Name))
-		if err != nil {
-			return "", err
-		}
+func extractLayer(layer v1.Layer, workdir string) error {
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
 
-		_, err = f.Write(b)
-		if err != nil {
-			return "", err
-		}
+	return extractTar(tar.NewReader(rc), workdir)
+}
+
+// dowloadSource writes the terraform code of the source in a new workdir and
+// returns it with the revision downloaded. The revision pins the download to
+// a revision returned by ResolveSource, when the protocol supports it.
+func (t terraformBackend) dowloadSource(ctx context.Context, source string, credentials map[string][]byte, revision string) (string, string, error) {
+	s := strings.SplitN(source, "://", 2)
+	if len(s) <= 1 {
+		return "", "", fmt.Errorf("invalid source. Plese use protocol://source-url.")
 	}
 
-	return workdir, nil
+	protocol, sourceUrl := s[0], s[1]
+	switch protocol {
+	case "git":
+		return t.gitDownload(ctx, sourceUrl, credentials, revision)
+	case "s3":
+		return t.s3Download(ctx, sourceUrl, credentials, revision)
+	case "file":
+		return t.fileDownload(sourceUrl)
+	case "https":
+		return t.httpDownload(ctx, sourceUrl, credentials)
+	case "oci":
+		return t.ociDownload(ctx, sourceUrl, revision)
+	default:
+		return "", "", fmt.Errorf("Invalid protocol")
+	}
 }
 
-func (t terraformBackend) dowloadSource(source string) (string, error) {
-	s := strings.Split(source, "://")
+// ResolveSource returns an immutable identifier of the source content, so
+// executions can detect when a mutable reference (e.g. a tag) changes.
+func (t terraformBackend) ResolveSource(ctx context.Context, source string, credentials map[string][]byte) (string, error) {
+	s := strings.SplitN(source, "://", 2)
 	if len(s) <= 1 {
 		return "", fmt.Errorf("invalid source. Plese use protocol://source-url.")
 	}
@@ -72,12 +106,22 @@ func (t terraformBackend) dowloadSource(source string) (string, error) {
 	protocol, sourceUrl := s[0], s[1]
 	switch protocol {
 	case "git":
-		return "", nil
+		return resolveGitSource(ctx, sourceUrl, credentials)
 	case "s3":
-		return "", nil
+		return resolveS3Source(ctx, sourceUrl, credentials)
+	case "file":
+		return resolveFileSource(sourceUrl)
+	case "https":
+		_, checksum, err := parseHttpSource(sourceUrl)
+		return checksum, err
 	case "oci":
-		return t.ociDownload(sourceUrl)
+		digest, err := crane.Digest(sourceUrl, crane.WithContext(ctx))
+		if err != nil {
+			return "", err
+		}
+
+		return digest, nil
 	default:
-		return "", fmt.Errorf("Invalid protocol")
+		return source, nil
 	}
 }
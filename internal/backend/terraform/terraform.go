@@ -1,6 +1,8 @@
 package terraform
 
 import (
+	"context"
+
 	"github.com/hashicorp/terraform-exec/tfexec"
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
 	"go.uber.org/zap"
@@ -12,12 +14,20 @@ type TerraformApplyInput struct {
 	TaskInputs       []commonv1alpha1.InfraTaskInput
 	PreviousState    string
 	PreviousLockDeps string
+	// Credentials of the source, e.g. the username and password of a private
+	// git repository
+	Credentials map[string][]byte
+	// Revision pins the source to a revision returned by ResolveSource
+	Revision string
 }
 
 type TerraformApplyResult struct {
 	Outputs          map[string]tfexec.OutputMeta
 	DependenciesLock string
 	State            string
+	// SourceRevision is the revision of the source applied, e.g. the commit of
+	// a git source or the digest of an oci artifact
+	SourceRevision string
 }
 
 type TerraformDestroyInput struct {
@@ -26,11 +36,41 @@ type TerraformDestroyInput struct {
 	TaskInputs       []commonv1alpha1.InfraTaskInput
 	PreviousState    string
 	PreviousLockDeps string
+	Credentials      map[string][]byte
+	Revision         string
+}
+
+type TerraformPlanInput struct {
+	Source           string
+	Version          string
+	TaskInputs       []commonv1alpha1.InfraTaskInput
+	PreviousState    string
+	PreviousLockDeps string
+	Credentials      map[string][]byte
+	Revision         string
+	Destroy          bool
+}
+
+// PlannedOutput is an output as it will be after the apply, Unknown outputs
+// are only known after the apply and have no value.
+type PlannedOutput struct {
+	tfexec.OutputMeta
+	Unknown bool
+}
+
+type TerraformPlanResult struct {
+	Outputs  map[string]PlannedOutput
+	Add      int
+	Change   int
+	Destroy  int
+	Rendered string
 }
 
 type TerraformBackend interface {
-	Apply(input TerraformApplyInput) (TerraformApplyResult, error)
-	Destroy(input TerraformDestroyInput) error
+	Apply(ctx context.Context, input TerraformApplyInput) (TerraformApplyResult, error)
+	Destroy(ctx context.Context, input TerraformDestroyInput) error
+	Plan(ctx context.Context, input TerraformPlanInput) (TerraformPlanResult, error)
+	ResolveSource(ctx context.Context, source string, credentials map[string][]byte) (string, error)
 }
 
 type terraformBackend struct {
@@ -0,0 +1,186 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/task"
+)
+
+// writeVarsFile renders the task inputs as exec.tfvars.json, the values are
+// checked against the variable types declared in the module.
+func writeVarsFile(workdirPath string, inputs []commonv1alpha1.InfraTaskInput) (string, error) {
+	module, diags := tfconfig.LoadModule(workdirPath)
+	if diags.HasErrors() {
+		return "", fmt.Errorf("failed to read module variables: %w", diags.Err())
+	}
+
+	vars := map[string]json.RawMessage{}
+	for _, i := range inputs {
+		value, err := EncodeInputValue(i)
+		if err != nil {
+			return "", err
+		}
+
+		if variable, ok := module.Variables[i.Key]; ok {
+			err = validateInputValue(i.Key, value, variable.Type)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		vars[i.Key] = value
+	}
+
+	rawVars, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	execVarsFilePath := filepath.Join(workdirPath, "exec.tfvars.json")
+	err = os.WriteFile(execVarsFilePath, rawVars, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return execVarsFilePath, nil
+}
+
+// EncodeInputValue encodes the value of an input as JSON according to its
+// type, inputs without type are strings.
+func EncodeInputValue(input commonv1alpha1.InfraTaskInput) (json.RawMessage, error) {
+	if input.Type == "" || input.Type == task.StringInputType {
+		return json.Marshal(input.Value)
+	}
+
+	if input.Type == task.BoolInputType {
+		value, err := strconv.ParseBool(strings.TrimSpace(input.Value))
+		if err != nil {
+			return nil, fmt.Errorf("input %s must be a bool, got %q", input.Key, input.Value)
+		}
+
+		return json.Marshal(value)
+	}
+
+	value, err := decodeJSON([]byte(input.Value))
+	if err != nil && input.Type != task.NumberInputType {
+		return nil, fmt.Errorf("input %s must be valid JSON for type %s: %s", input.Key, input.Type, err.Error())
+	}
+
+	switch input.Type {
+	case task.NumberInputType:
+		if _, ok := value.(json.Number); !ok || err != nil {
+			return nil, fmt.Errorf("input %s must be a number, got %q", input.Key, input.Value)
+		}
+	case task.ListInputType:
+		if _, ok := value.([]interface{}); !ok {
+			return nil, fmt.Errorf("input %s must be a JSON list, got %q", input.Key, input.Value)
+		}
+	case task.MapInputType:
+		if _, ok := value.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("input %s must be a JSON object, got %q", input.Key, input.Value)
+		}
+	case task.JSONInputType:
+	default:
+		return nil, fmt.Errorf("invalid type %s of input %s", input.Type, input.Key)
+	}
+
+	return json.Marshal(value)
+}
+
+// decodeJSON decodes a single JSON value keeping numbers as json.Number, so
+// integers above 2^53 aren't rounded when the value is encoded again.
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err == io.EOF {
+		return nil, errors.New("unexpected end of JSON input")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+
+	return value, nil
+}
+
+// validateInputValue checks an encoded value against a terraform type
+// constraint, following the conversions terraform does for primitive types.
+func validateInputValue(key string, value json.RawMessage, variableType string) error {
+	decoded, err := decodeJSON(value)
+	if err != nil {
+		return err
+	}
+
+	compatible := true
+	switch getTypeKind(variableType) {
+	case "string":
+		switch decoded.(type) {
+		case []interface{}, map[string]interface{}:
+			compatible = false
+		}
+	case "number":
+		switch v := decoded.(type) {
+		case json.Number:
+		case string:
+			_, err := strconv.ParseFloat(v, 64)
+			compatible = err == nil
+		default:
+			compatible = false
+		}
+	case "bool":
+		switch v := decoded.(type) {
+		case bool:
+		case string:
+			compatible = v == "true" || v == "false"
+		default:
+			compatible = false
+		}
+	case "list":
+		_, compatible = decoded.([]interface{})
+	case "map":
+		_, compatible = decoded.(map[string]interface{})
+	}
+
+	if !compatible {
+		return fmt.Errorf("input %s with value %s doesn't match the type %s of the module variable", key, string(value), variableType)
+	}
+
+	return nil
+}
+
+// getTypeKind returns the kind of a type constraint like list(string) or
+// object({ name = string }), empty for unknown or any types.
+func getTypeKind(variableType string) string {
+	name := strings.TrimSpace(variableType)
+	if index := strings.Index(name, "("); index >= 0 {
+		name = strings.TrimSpace(name[:index])
+	}
+
+	switch name {
+	case "string", "number", "bool":
+		return name
+	case "list", "set", "tuple":
+		return "list"
+	case "map", "object":
+		return "map"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,140 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+const testModule = `
+variable "name" {
+  type = string
+}
+
+variable "subnets" {
+  type = list(string)
+}
+
+variable "tags" {
+  type = map(string)
+}
+
+variable "instances" {
+  type = number
+}
+
+variable "settings" {}
+
+variable "network" {
+  type = object({
+    vpc     = object({ id = string })
+    subnets = list(object({ id = string, az = string }))
+  })
+}
+
+variable "rules" {
+  type = list(object({
+    port  = number
+    cidrs = list(string)
+  }))
+}
+`
+
+func TestWriteVarsFile(t *testing.T) {
+	workdirPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(workdirPath, "main.tf"), []byte(testModule), 0644))
+
+	execVarsFilePath, err := writeVarsFile(workdirPath, []commonv1alpha1.InfraTaskInput{
+		{Key: "name", Value: "my \"topic\"\nname"},
+		{Key: "subnets", Value: `["a", "b"]`, Type: "list"},
+		{Key: "tags", Value: `{"env": "dev"}`, Type: "map"},
+		{Key: "instances", Value: "3"},
+		{Key: "settings", Value: `{"nested": [1, true]}`, Type: "json"},
+		{Key: "network", Value: `{"vpc": {"id": "vpc-1"}, "subnets": [{"id": "subnet-a", "az": "us-east-1a"}]}`, Type: "map"},
+		{Key: "rules", Value: `[{"port": 443, "cidrs": ["0.0.0.0/0"]}, {"port": 22, "cidrs": []}]`, Type: "list"},
+	})
+	assert.NoError(t, err)
+
+	rawVars, err := os.ReadFile(execVarsFilePath)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "my \"topic\"\nname",
+		"subnets": ["a", "b"],
+		"tags": {"env": "dev"},
+		"instances": "3",
+		"settings": {"nested": [1, true]},
+		"network": {"vpc": {"id": "vpc-1"}, "subnets": [{"id": "subnet-a", "az": "us-east-1a"}]},
+		"rules": [{"port": 443, "cidrs": ["0.0.0.0/0"]}, {"port": 22, "cidrs": []}]
+	}`, string(rawVars))
+
+	_, err = writeVarsFile(workdirPath, []commonv1alpha1.InfraTaskInput{{Key: "subnets", Value: "a,b"}})
+	assert.EqualError(t, err, `input subnets with value "a,b" doesn't match the type list(string) of the module variable`)
+
+	_, err = writeVarsFile(workdirPath, []commonv1alpha1.InfraTaskInput{{Key: "instances", Value: "three"}})
+	assert.EqualError(t, err, `input instances with value "three" doesn't match the type number of the module variable`)
+
+	_, err = writeVarsFile(workdirPath, []commonv1alpha1.InfraTaskInput{{Key: "rules", Value: `{"port": 443}`, Type: "json"}})
+	assert.ErrorContains(t, err, "input rules with value {\"port\":443} doesn't match the type list(object")
+
+	_, err = writeVarsFile(workdirPath, []commonv1alpha1.InfraTaskInput{{Key: "network", Value: `[{"vpc": {"id": "vpc-1"}}]`, Type: "json"}})
+	assert.ErrorContains(t, err, "doesn't match the type object(")
+}
+
+func TestEncodeInputValue(t *testing.T) {
+	cases := map[string]struct {
+		input    commonv1alpha1.InfraTaskInput
+		expected string
+		err      string
+	}{
+		"nested object": {
+			input:    commonv1alpha1.InfraTaskInput{Key: "network", Value: `{"vpc": {"id": "vpc-1", "tags": {"env": "dev"}}}`, Type: "map"},
+			expected: `{"vpc":{"id":"vpc-1","tags":{"env":"dev"}}}`,
+		},
+		"list of objects": {
+			input:    commonv1alpha1.InfraTaskInput{Key: "rules", Value: `[{"port": 443, "cidrs": ["0.0.0.0/0"]}, {"port": 22}]`, Type: "list"},
+			expected: `[{"cidrs":["0.0.0.0/0"],"port":443},{"port":22}]`,
+		},
+		"list of objects as json": {
+			input:    commonv1alpha1.InfraTaskInput{Key: "rules", Value: `[{"port": 443}]`, Type: "json"},
+			expected: `[{"port":443}]`,
+		},
+		"list of objects as map": {
+			input: commonv1alpha1.InfraTaskInput{Key: "rules", Value: `[{"port": 443}]`, Type: "map"},
+			err:   `input rules must be a JSON object, got "[{\"port\": 443}]"`,
+		},
+		"nested object as list": {
+			input: commonv1alpha1.InfraTaskInput{Key: "network", Value: `{"vpc": {"id": "vpc-1"}}`, Type: "list"},
+			err:   `input network must be a JSON list, got "{\"vpc\": {\"id\": \"vpc-1\"}}"`,
+		},
+		"invalid nested object": {
+			input: commonv1alpha1.InfraTaskInput{Key: "network", Value: `{"vpc": {"id": }}`, Type: "map"},
+			err:   "input network must be valid JSON for type map",
+		},
+	}
+
+	for name, c := range cases {
+		value, err := EncodeInputValue(c.input)
+		if c.err != "" {
+			assert.ErrorContains(t, err, c.err, name)
+			continue
+		}
+
+		assert.NoError(t, err, name)
+		assert.JSONEq(t, c.expected, string(value), name)
+	}
+
+	// JSONEq compares numbers as float64, large integers are checked as text
+	value, err := EncodeInputValue(commonv1alpha1.InfraTaskInput{Key: "account_id", Value: "9007199254740993", Type: "number"})
+	assert.NoError(t, err)
+	assert.Equal(t, "9007199254740993", string(value))
+
+	value, err = EncodeInputValue(commonv1alpha1.InfraTaskInput{Key: "accounts", Value: `{"ids": [9007199254740993, 1.5]}`, Type: "map"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ids":[9007199254740993,1.5]}`, string(value))
+
+	_, err = EncodeInputValue(commonv1alpha1.InfraTaskInput{Key: "account_id", Value: "1 2", Type: "number"})
+	assert.EqualError(t, err, `input account_id must be a number, got "1 2"`)
+}
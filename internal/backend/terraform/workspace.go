@@ -0,0 +1,83 @@
+package terraform
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"go.uber.org/zap"
+)
+
+type workspaceInput struct {
+	Source           string
+	Version          string
+	TaskInputs       []commonv1alpha1.InfraTaskInput
+	PreviousState    string
+	PreviousLockDeps string
+	Credentials      map[string][]byte
+	Revision         string
+}
+
+// workspace is a task source initialized with the state of its last execution,
+// ready to run terraform commands.
+type workspace struct {
+	tf             *tfexec.Terraform
+	workdirPath    string
+	varsFilePath   string
+	sourceRevision string
+}
+
+// prepareWorkspace downloads the source, installs terraform, writes the vars
+// file and runs terraform init with the last lock file and state of the task.
+func (t terraformBackend) prepareWorkspace(ctx context.Context, input workspaceInput, initOptions ...tfexec.InitOption) (workspace, error) {
+	t.logger.Info("get terrafrom from source", zap.String("source", input.Source))
+	workdirPath, sourceRevision, err := t.dowloadSource(ctx, input.Source, input.Credentials, input.Revision)
+	if err != nil {
+		return workspace{}, err
+	}
+
+	t.logger.Info("install terraform by version", zap.String("version", input.Version))
+	terraformPath, err := t.install(ctx, input.Version)
+	if err != nil {
+		return workspace{}, err
+	}
+
+	t.logger.Info("creating terraform vars file", zap.String("workdir", workdirPath))
+	varsFilePath, err := writeVarsFile(workdirPath, input.TaskInputs)
+	if err != nil {
+		return workspace{}, err
+	}
+
+	tf, err := tfexec.NewTerraform(workdirPath, terraformPath)
+	if err != nil {
+		return workspace{}, err
+	}
+
+	if input.PreviousLockDeps != "" {
+		err = persistDependenciesLock(input.PreviousLockDeps, workdirPath)
+		if err != nil {
+			return workspace{}, err
+		}
+	}
+
+	t.logger.Info("executing terraform init", zap.String("workdir", workdirPath), zap.String("tfpath", terraformPath))
+	err = tf.Init(ctx, initOptions...)
+	if err != nil {
+		t.logger.Error(err.Error())
+		return workspace{}, err
+	}
+
+	if input.PreviousState != "" {
+		err := persistPreviousState(input.PreviousState, workdirPath)
+		if err != nil {
+			return workspace{}, err
+		}
+	}
+
+	return workspace{
+		tf:             tf,
+		workdirPath:    workdirPath,
+		varsFilePath:   varsFilePath,
+		sourceRevision: sourceRevision,
+	}, nil
+}
@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/annotation"
 	"github.com/octopipe/cloudx/internal/controller/utils"
 	"github.com/octopipe/cloudx/internal/customerror"
 	"github.com/octopipe/cloudx/internal/pipeline"
@@ -16,6 +18,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -50,16 +53,34 @@ func (c *controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, nil
 	}
 
-	if currentInfra.Status.LastExecution.Status == pipeline.InfraRunningStatus {
+	if isExecutionInProgress(currentInfra.Status.LastExecution) || currentInfra.Status.LastPlan.Status == pipeline.InfraRunningStatus {
 		c.logger.Info("This infra has runner in execution, enqueue this request")
 		return ctrl.Result{
 			RequeueAfter: time.Second * 2,
 		}, nil
 	}
 
-	action := "APPLY"
+	options := pipeline.ExecutionOptions{}
+	if rawTargets := currentInfra.Annotations[annotation.TargetsAnnotation]; rawTargets != "" {
+		options.Targets = strings.Split(rawTargets, ",")
+	}
+
+	action := pipeline.ApplyAction
+	requestedAction := currentInfra.Annotations[annotation.ActionAnnotation]
 	if len(currentInfra.Finalizers) > 0 {
-		action = "DESTROY"
+		action = pipeline.DestroyAction
+		options.Targets = nil
+	} else if requestedAction == pipeline.PlanAction {
+		action = pipeline.PlanAction
+		options.Targets = nil
+	} else if requestedAction == pipeline.DestroyAction && len(options.Targets) > 0 {
+		// only targeted destroys can be requested, the whole infra is
+		// destroyed by its deletion
+		action = pipeline.DestroyAction
+	}
+
+	if action == pipeline.ApplyAction {
+		options.ReconcileMode = currentInfra.Annotations[annotation.ReconcileModeAnnotation]
 	}
 
 	c.logger.Info("get provider config from infra...")
@@ -87,7 +108,7 @@ func (c *controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	c.logger.Info("verify enverionment to create runner")
 	if os.Getenv("ENV") != "local" {
 		c.logger.Info("creating runner...")
-		newRunner, err := c.NewRunner(action, *currentInfra, providerConfig, varsCreds)
+		newRunner, err := c.NewRunner(action, options, *currentInfra, providerConfig, varsCreds)
 		if err != nil {
 			c.logger.Error("Failed to create runner", zap.Error(err))
 			return c.persistError(err, currentInfra)
@@ -100,8 +121,26 @@ func (c *controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			return c.persistError(customErr, currentInfra)
 		}
 
-		currentInfra.Status.LastExecution.Status = pipeline.InfraRunningStatus
-		currentInfra.Status.LastExecution.StartedAt = time.Now().Format(time.RFC3339)
+		if hasRequestedAction(*currentInfra) {
+			err = c.clearRequestedAction(ctx, currentInfra)
+			if err != nil {
+				c.logger.Error("Failed to clear requested action", zap.Error(err))
+				return ctrl.Result{Requeue: false}, err
+			}
+		}
+
+		if action == pipeline.PlanAction {
+			currentInfra.Status.LastPlan = commonv1alpha1.ExecutionStatus{
+				Status:    pipeline.InfraRunningStatus,
+				StartedAt: time.Now().Format(time.RFC3339),
+			}
+		} else {
+			currentInfra.Status.LastExecution.Status = pipeline.InfraRunningStatus
+			currentInfra.Status.LastExecution.StartedAt = time.Now().Format(time.RFC3339)
+			// approvals are only valid for the execution they were given to
+			currentInfra.Status.Approvals = nil
+		}
+
 		err = utils.UpdateInfraStatus(c.Client, *currentInfra)
 		if err != nil {
 			c.logger.Error("Failed to update infra status", zap.Error(err))
@@ -112,6 +151,38 @@ func (c *controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	return ctrl.Result{Requeue: false}, nil
 }
 
+// isExecutionInProgress checks if the runner of the last execution is still
+// alive, runners waiting for an approval keep running until it is given.
+func isExecutionInProgress(execution commonv1alpha1.ExecutionStatus) bool {
+	return execution.Status == pipeline.InfraRunningStatus || execution.Status == pipeline.InfraWaitingApprovalStatus
+}
+
+func hasRequestedAction(currentInfra commonv1alpha1.Infra) bool {
+	for _, a := range []string{annotation.ActionAnnotation, annotation.ReconcileModeAnnotation, annotation.TargetsAnnotation} {
+		if _, ok := currentInfra.Annotations[a]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clearRequestedAction removes the action, reconcile mode and targets requested
+// through the infra annotations, so the next reconcile falls back to the default action.
+func (c *controller) clearRequestedAction(ctx context.Context, currentInfra *commonv1alpha1.Infra) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		err := c.Get(ctx, types.NamespacedName{Name: currentInfra.Name, Namespace: currentInfra.Namespace}, currentInfra)
+		if err != nil {
+			return err
+		}
+
+		delete(currentInfra.Annotations, annotation.ActionAnnotation)
+		delete(currentInfra.Annotations, annotation.ReconcileModeAnnotation)
+		delete(currentInfra.Annotations, annotation.TargetsAnnotation)
+		return c.Update(ctx, currentInfra)
+	})
+}
+
 func (c controller) getCreds(providerConfig commonv1alpha1.ProviderConfig) ([]v1.EnvVar, error) {
 	if providerConfig.Spec.Type == "AWS" {
 		creds, err := c.provider.GetCreds(context.Background(), providerConfig)
@@ -123,6 +194,8 @@ func (c controller) getCreds(providerConfig commonv1alpha1.ProviderConfig) ([]v1
 			{Name: "AWS_ACCESS_KEY_ID", Value: creds.AccessKeyId},
 			{Name: "AWS_SECRET_ACCESS_KEY", Value: creds.AccessKey},
 			{Name: "AWS_SESSION_TOKEN", Value: creds.SessionToken},
+			// used by the runner to download s3 task sources
+			{Name: "AWS_REGION", Value: providerConfig.Spec.AWSConfig.Region},
 		}
 
 		return vars, nil
@@ -65,6 +65,26 @@ func (s *RPCServer) SetExecutionStatus(args *RPCSetExecutionStatusArgs, reply *i
 	return nil
 }
 
+func (s *RPCServer) SetPlanStatus(args *RPCSetExecutionStatusArgs, reply *int) error {
+	s.logger.Info("received call", zap.String("method", "RPCServer.SetPlanStatus"), zap.String("infra", args.Ref.String()))
+	infra := &commonv1alpha1.Infra{}
+	err := s.Get(context.Background(), args.Ref, infra)
+	if err != nil {
+		s.logger.Error("Failed to get current plan", zap.String("method", "RPCServer.SetPlanStatus"), zap.String("infra", args.Ref.String()), zap.Error(err))
+		return err
+	}
+
+	infra.Status.LastPlan = args.ExecutionStatus
+	s.logger.Info("updating current plan status", zap.String("method", "RPCServer.SetPlanStatus"), zap.String("status", args.ExecutionStatus.Status))
+	err = utils.UpdateInfraStatus(s.Client, *infra)
+	if err != nil {
+		s.logger.Error("Failed to update current plan status", zap.String("method", "RPCServer.SetPlanStatus"), zap.String("infra", args.Ref.String()), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
 type RPCSetRunnerTimeoutArgs struct {
 	Tasks []commonv1alpha1.TaskExecutionStatus
 	Ref   types.NamespacedName
@@ -3,9 +3,11 @@ package infra
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/pipeline"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -16,7 +18,7 @@ type Runner struct {
 	Service *v1.Service
 }
 
-func (c *controller) NewRunner(action string, infra commonv1alpha1.Infra, providerConfig commonv1alpha1.ProviderConfig, varsCreds []v1.EnvVar) (Runner, error) {
+func (c *controller) NewRunner(action string, options pipeline.ExecutionOptions, infra commonv1alpha1.Infra, providerConfig commonv1alpha1.ProviderConfig, varsCreds []v1.EnvVar) (Runner, error) {
 	vFalse := false
 	vTrue := true
 	vUser := int64(65532)
@@ -75,6 +77,13 @@ func (c *controller) NewRunner(action string, infra commonv1alpha1.Infra, provid
 		},
 	}
 
+	if maxParallelTasks := os.Getenv("MAX_PARALLEL_TASKS"); maxParallelTasks != "" {
+		defaultVars = append(defaultVars, v1.EnvVar{
+			Name:  "MAX_PARALLEL_TASKS",
+			Value: maxParallelTasks,
+		})
+	}
+
 	infraRef := types.NamespacedName{
 		Name:      infra.GetName(),
 		Namespace: infra.GetNamespace(),
@@ -83,6 +92,13 @@ func (c *controller) NewRunner(action string, infra commonv1alpha1.Infra, provid
 	defaultVars = append(defaultVars, varsCreds...)
 
 	args := []string{"/usr/local/bin/runner", action, infraRef.String()}
+	if options.ReconcileMode != "" {
+		args = append(args, fmt.Sprintf("--reconcile-mode=%s", options.ReconcileMode))
+	}
+
+	if len(options.Targets) > 0 {
+		args = append(args, fmt.Sprintf("--targets=%s", strings.Join(options.Targets, ",")))
+	}
 
 	newRunnerObject := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,267 @@
+package expression
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/octopipe/cloudx/internal/lex"
+)
+
+// Value is the result of an expression, Data holds JSON decoded values:
+// string, float64, bool, nil, []interface{} or map[string]interface{}.
+type Value struct {
+	Data      interface{}
+	Sensitive bool
+}
+
+// Resolver resolves the paths of an expression, it returns the value and how
+// many segments of the path were consumed to find it. The remaining segments
+// are applied to the value as map keys.
+type Resolver interface {
+	Resolve(origin string, path []string) (Value, int, error)
+}
+
+// NotFoundError is returned when a path doesn't exist, default() falls back to
+// its second argument on it.
+type NotFoundError struct {
+	Offset  int
+	Message string
+}
+
+func (e NotFoundError) Error() string {
+	return lex.Error{Offset: e.Offset, Message: e.Message}.Error()
+}
+
+// UnknownError is returned when a path is only known after the apply, e.g. an
+// output of a task that was only planned. Expressions using it are unknown too.
+type UnknownError struct {
+	Offset  int
+	Message string
+}
+
+func (e UnknownError) Error() string {
+	return lex.Error{Offset: e.Offset, Message: e.Message}.Error()
+}
+
+// Evaluate evaluates a parsed expression with the data of the resolver.
+func Evaluate(node Node, resolver Resolver) (Value, error) {
+	switch n := node.(type) {
+	case StringNode:
+		return Value{Data: n.Value}, nil
+	case NumberNode:
+		return Value{Data: n.Value}, nil
+	case BoolNode:
+		return Value{Data: n.Value}, nil
+	case PathNode:
+		value, consumed, err := resolver.Resolve(n.Origin, n.Segments)
+		if err != nil {
+			return Value{}, wrapError(n.Offset, err)
+		}
+
+		for _, segment := range n.Segments[consumed:] {
+			value, err = getAttr(value, segment)
+			if err != nil {
+				return Value{}, wrapError(n.Offset, err)
+			}
+		}
+
+		return value, nil
+	case AttrNode:
+		target, err := Evaluate(n.Target, resolver)
+		if err != nil {
+			return Value{}, err
+		}
+
+		value, err := getAttr(target, n.Name)
+		if err != nil {
+			return Value{}, wrapError(n.Offset, err)
+		}
+
+		return value, nil
+	case IndexNode:
+		return evaluateIndex(n, resolver)
+	case ConcatNode:
+		result := ""
+		sensitive := false
+		for _, part := range n.Parts {
+			value, err := Evaluate(part, resolver)
+			if err != nil {
+				return Value{}, err
+			}
+
+			result += ToString(value.Data)
+			sensitive = sensitive || value.Sensitive
+		}
+
+		return Value{Data: result, Sensitive: sensitive}, nil
+	case CallNode:
+		return evaluateCall(n, resolver)
+	default:
+		return Value{}, fmt.Errorf("unknown expression node %T", node)
+	}
+}
+
+func evaluateIndex(n IndexNode, resolver Resolver) (Value, error) {
+	target, err := Evaluate(n.Target, resolver)
+	if err != nil {
+		return Value{}, err
+	}
+
+	index, err := Evaluate(n.Index, resolver)
+	if err != nil {
+		return Value{}, err
+	}
+
+	sensitive := target.Sensitive || index.Sensitive
+	switch data := target.Data.(type) {
+	case []interface{}:
+		i, ok := index.Data.(float64)
+		if !ok || i != math.Trunc(i) {
+			return Value{}, lex.Error{Offset: n.Offset, Message: fmt.Sprintf("list index must be an integer, got %s", typeName(index.Data))}
+		}
+
+		if i < 0 || int(i) >= len(data) {
+			return Value{}, lex.Error{Offset: n.Offset, Message: fmt.Sprintf("index %d out of range for list of length %d", int(i), len(data))}
+		}
+
+		return Value{Data: data[int(i)], Sensitive: sensitive}, nil
+	case map[string]interface{}:
+		value, err := getAttr(Value{Data: data, Sensitive: sensitive}, ToString(index.Data))
+		if err != nil {
+			return Value{}, wrapError(n.Offset, err)
+		}
+
+		return value, nil
+	default:
+		return Value{}, lex.Error{Offset: n.Offset, Message: fmt.Sprintf("can't index a value of type %s", typeName(target.Data))}
+	}
+}
+
+func evaluateCall(n CallNode, resolver Resolver) (Value, error) {
+	if n.Name == "default" {
+		value, err := Evaluate(n.Args[0], resolver)
+		if err == nil && !isEmpty(value.Data) {
+			return value, nil
+		}
+
+		if err != nil && !errors.As(err, &NotFoundError{}) {
+			return Value{}, err
+		}
+
+		return Evaluate(n.Args[1], resolver)
+	}
+
+	args := []interface{}{}
+	sensitive := false
+	for _, arg := range n.Args {
+		value, err := Evaluate(arg, resolver)
+		if err != nil {
+			return Value{}, err
+		}
+
+		args = append(args, value.Data)
+		sensitive = sensitive || value.Sensitive
+	}
+
+	result, err := functions[n.Name].call(args)
+	if err != nil {
+		return Value{}, lex.Error{Offset: n.Offset, Message: err.Error()}
+	}
+
+	return Value{Data: result, Sensitive: sensitive}, nil
+}
+
+func getAttr(value Value, name string) (Value, error) {
+	data, ok := value.Data.(map[string]interface{})
+	if !ok {
+		return Value{}, fmt.Errorf("can't get attribute %s of a value of type %s", name, typeName(value.Data))
+	}
+
+	attr, ok := data[name]
+	if !ok {
+		return Value{}, NotFoundError{Message: fmt.Sprintf("not found attribute %s", name)}
+	}
+
+	return Value{Data: attr, Sensitive: value.Sensitive}, nil
+}
+
+// wrapError adds the offset of the expression node to resolution errors.
+func wrapError(offset int, err error) error {
+	var lexError lex.Error
+	if errors.As(err, &lexError) {
+		return err
+	}
+
+	var notFound NotFoundError
+	if errors.As(err, &notFound) {
+		notFound.Offset = offset
+		return notFound
+	}
+
+	var unknown UnknownError
+	if errors.As(err, &unknown) {
+		unknown.Offset = offset
+		return unknown
+	}
+
+	return lex.Error{Offset: offset, Message: err.Error()}
+}
+
+func isEmpty(data interface{}) bool {
+	switch d := data.(type) {
+	case nil:
+		return true
+	case string:
+		return d == ""
+	case []interface{}:
+		return len(d) == 0
+	case map[string]interface{}:
+		return len(d) == 0
+	default:
+		return false
+	}
+}
+
+// ToString converts a value to its interpolated form, lists and maps are
+// written as JSON.
+func ToString(data interface{}) string {
+	switch d := data.(type) {
+	case nil:
+		return ""
+	case string:
+		return d
+	case bool:
+		return strconv.FormatBool(d)
+	case float64:
+		return strconv.FormatFloat(d, 'f', -1, 64)
+	default:
+		encoded, err := json.Marshal(d)
+		if err != nil {
+			return fmt.Sprintf("%v", d)
+		}
+
+		return string(encoded)
+	}
+}
+
+func typeName(data interface{}) string {
+	switch data.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	default:
+		return fmt.Sprintf("%T", data)
+	}
+}
@@ -0,0 +1,158 @@
+package expression
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type mapResolver map[string]Value
+
+func (r mapResolver) Resolve(origin string, path []string) (Value, int, error) {
+	if len(path) < 2 {
+		return Value{}, 0, fmt.Errorf("malformed reference")
+	}
+
+	value, ok := r[fmt.Sprintf("%s.%s.%s", origin, path[0], path[1])]
+	if !ok {
+		return Value{}, 0, NotFoundError{Message: fmt.Sprintf("not found %s", path[1])}
+	}
+
+	return value, 2, nil
+}
+
+type ExpressionTestSuite struct {
+	suite.Suite
+	resolver mapResolver
+}
+
+func (suite *ExpressionTestSuite) SetupTest() {
+	suite.resolver = mapResolver{
+		"this.vpc.name":       {Data: "main"},
+		"this.vpc.subnet_ids": {Data: []interface{}{"subnet-a", "subnet-b"}},
+		"this.db.endpoint":    {Data: map[string]interface{}{"host": "db.local", "port": float64(5432)}},
+		"this.db.password":    {Data: "secret", Sensitive: true},
+	}
+}
+
+func (suite *ExpressionTestSuite) evaluate(template string) (string, bool, error) {
+	t, err := ParseTemplate(template)
+	if err != nil {
+		return "", false, err
+	}
+
+	return t.Evaluate(suite.resolver)
+}
+
+func (suite *ExpressionTestSuite) TestEvaluate() {
+	cases := map[string]string{
+		"{{ this.vpc.name }}":                                     "main",
+		"id: {{ this.vpc.subnet_ids[1] }}!":                       "id: subnet-b!",
+		"{{ this.db.endpoint.host }}:{{ this.db.endpoint.port }}": "db.local:5432",
+		"{{ this.db.endpoint[\"host\"] }}":                        "db.local",
+		"{{ upper(this.vpc.name) + \"-\" + lower('VPC') }}":       "MAIN-vpc",
+		"{{ join(\",\", this.vpc.subnet_ids) }}":                  "subnet-a,subnet-b",
+		"{{ split(\",\", \"a,b\")[1] }}":                          "b",
+		"{{ base64encode(this.vpc.name) }}":                       "bWFpbg==",
+		"{{ jsonencode(this.vpc.subnet_ids) }}":                   `["subnet-a","subnet-b"]`,
+		"{{ format(\"%s:%d\", this.vpc.name, 80) }}":              "main:80",
+		"{{ format(\"%s-%s\", \"node\", 3) }}":                    "node-3",
+		"{{ format(\"%v/%v\", 1.5, true) }}":                      "1.5/true",
+		"{{ format(\"%03d|%-4s|\", 7, \"ab\") }}":                 "007|ab  |",
+		"{{ format(\"%.2f %x\", 2, 255) }}":                       "2.00 ff",
+		"{{ format(\"%q %s\", 10, this.vpc.subnet_ids) }}":        `"10" ["subnet-a","subnet-b"]`,
+		"{{ default(this.vpc.missing, \"fallback\") }}":           "fallback",
+		"{{ default(this.db.endpoint.user, 'admin') }}":           "admin",
+		"{{ default(this.vpc.name, \"fallback\") }}":              "main",
+	}
+
+	for template, expected := range cases {
+		value, _, err := suite.evaluate(template)
+		assert.NoError(suite.T(), err, template)
+		assert.Equal(suite.T(), expected, value, template)
+	}
+}
+
+func (suite *ExpressionTestSuite) TestSensitive() {
+	_, sensitive, err := suite.evaluate("{{ this.vpc.name }}")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), sensitive)
+
+	value, sensitive, err := suite.evaluate("{{ upper(this.db.password) }}")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "SECRET", value)
+	assert.True(suite.T(), sensitive)
+}
+
+func (suite *ExpressionTestSuite) TestErrorColumns() {
+	cases := map[string]string{
+		"abc {{ this.vpc.missing }}":          "column 8: not found missing",
+		"{{ this.vpc.subnet_ids[2] }}":        "column 23: index 2 out of range for list of length 2",
+		"{{ unknown(this.vpc.name) }}":        "column 4: unknown function unknown",
+		"{{ upper(this.vpc.name, 1) }}":       "column 4: wrong number of arguments for upper, expected 1",
+		"{{ this.vpc. }}":                     "column 14: expected an attribute name after the dot",
+		"{{ \"abc }}":                         "column 4: unterminated string",
+		"value {{ this.vpc.name":              "column 7: unclosed {{",
+		"{{ join(\",\", this.vpc.name) }}":    "column 4: join expects a list as second argument, got string",
+		"{{ this.vpc.name this.vpc.name }}":   "column 18: unexpected \"this\"",
+		"{{ this.vpc.name }} {{ this.db.x }}": "column 24: not found x",
+	}
+
+	for template, expected := range cases {
+		_, _, err := suite.evaluate(template)
+		if assert.Error(suite.T(), err, template) {
+			assert.Equal(suite.T(), expected, err.Error(), template)
+		}
+	}
+}
+
+func (suite *ExpressionTestSuite) TestReferences() {
+	t, err := ParseTemplate("{{ default(this.vpc.name, task-output.shared.name) }}-{{ this.db.endpoint.host }}")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []Reference{
+		{Origin: "this", Segments: []string{"vpc", "name"}, Offset: 11},
+		{Origin: "task-output", Segments: []string{"shared", "name"}, Offset: 26},
+		{Origin: "this", Segments: []string{"db", "endpoint", "host"}, Offset: 57},
+	}, t.References())
+}
+
+// unknownResolver resolves the outputs of the lb task as known after the apply.
+type unknownResolver struct {
+	mapResolver
+}
+
+func (r unknownResolver) Resolve(origin string, path []string) (Value, int, error) {
+	if len(path) > 1 && path[0] == "lb" {
+		return Value{}, 0, UnknownError{Message: fmt.Sprintf("%s is known after apply", path[1])}
+	}
+
+	return r.mapResolver.Resolve(origin, path)
+}
+
+func (suite *ExpressionTestSuite) TestUnknown() {
+	cases := map[string]string{
+		"{{ this.lb.arn }}":                           "column 4: arn is known after apply",
+		"name: {{ upper(this.lb.dns) }}":              "column 16: dns is known after apply",
+		"{{ this.vpc.name + \"-\" + this.lb.arn }}":   "column 26: arn is known after apply",
+		"{{ default(this.lb.arn, \"fallback\") }}":    "column 12: arn is known after apply",
+		"{{ this.vpc.subnet_ids[this.lb.index] }}":    "column 24: index is known after apply",
+		"{{ this.vpc.name }}-{{ this.lb.zone.name }}": "column 24: zone is known after apply",
+	}
+
+	for template, expected := range cases {
+		t, err := ParseTemplate(template)
+		assert.NoError(suite.T(), err, template)
+
+		_, _, err = t.Evaluate(unknownResolver{suite.resolver})
+		assert.ErrorAs(suite.T(), err, &UnknownError{}, template)
+		if assert.Error(suite.T(), err, template) {
+			assert.Equal(suite.T(), expected, err.Error(), template)
+		}
+	}
+}
+
+func TestExpressionTestSuite(t *testing.T) {
+	suite.Run(t, new(ExpressionTestSuite))
+}
@@ -0,0 +1,141 @@
+package expression
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type function struct {
+	minArgs int
+	// maxArgs is -1 for variadic functions
+	maxArgs int
+	call    func(args []interface{}) (interface{}, error)
+}
+
+func (f function) arity() string {
+	switch {
+	case f.maxArgs < 0:
+		return fmt.Sprintf("at least %d", f.minArgs)
+	case f.minArgs == f.maxArgs:
+		return fmt.Sprintf("%d", f.minArgs)
+	default:
+		return fmt.Sprintf("%d to %d", f.minArgs, f.maxArgs)
+	}
+}
+
+// functions available in expressions, default is evaluated lazily by the
+// evaluator because its first argument is allowed to fail.
+var functions = map[string]function{
+	"default":      {minArgs: 2, maxArgs: 2},
+	"join":         {minArgs: 2, maxArgs: 2, call: join},
+	"split":        {minArgs: 2, maxArgs: 2, call: split},
+	"upper":        {minArgs: 1, maxArgs: 1, call: upper},
+	"lower":        {minArgs: 1, maxArgs: 1, call: lower},
+	"base64encode": {minArgs: 1, maxArgs: 1, call: base64encode},
+	"jsonencode":   {minArgs: 1, maxArgs: 1, call: jsonencode},
+	"format":       {minArgs: 1, maxArgs: -1, call: format},
+}
+
+// join(separator, list)
+func join(args []interface{}) (interface{}, error) {
+	list, ok := args[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("join expects a list as second argument, got %s", typeName(args[1]))
+	}
+
+	items := []string{}
+	for _, item := range list {
+		items = append(items, ToString(item))
+	}
+
+	return strings.Join(items, ToString(args[0])), nil
+}
+
+// split(separator, string)
+func split(args []interface{}) (interface{}, error) {
+	items := []interface{}{}
+	for _, item := range strings.Split(ToString(args[1]), ToString(args[0])) {
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func upper(args []interface{}) (interface{}, error) {
+	return strings.ToUpper(ToString(args[0])), nil
+}
+
+func lower(args []interface{}) (interface{}, error) {
+	return strings.ToLower(ToString(args[0])), nil
+}
+
+func base64encode(args []interface{}) (interface{}, error) {
+	return base64.StdEncoding.EncodeToString([]byte(ToString(args[0]))), nil
+}
+
+func jsonencode(args []interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return string(encoded), nil
+}
+
+// format(format, args...) follows the fmt verbs, %s, %v and %q write the values
+// as they are interpolated and integral numbers are passed as integers to the
+// integer verbs, so %d works with terraform numbers.
+func format(args []interface{}) (interface{}, error) {
+	formatArgs := []interface{}{}
+	for _, arg := range args[1:] {
+		formatArgs = append(formatArgs, formatArg{data: arg})
+	}
+
+	return fmt.Sprintf(ToString(args[0]), formatArgs...), nil
+}
+
+// formatArg converts a value for the verb it is formatted with.
+type formatArg struct {
+	data interface{}
+}
+
+func (a formatArg) Format(f fmt.State, verb rune) {
+	var value interface{} = a.data
+	switch verb {
+	case 's', 'v', 'q':
+		value = ToString(a.data)
+	case 'd', 'b', 'o', 'x', 'X', 'c', 'U':
+		if n, ok := a.data.(float64); ok && n == math.Trunc(n) && math.Abs(n) < 1e15 {
+			value = int64(n)
+		}
+	}
+
+	fmt.Fprintf(f, getFormatDirective(f, verb), value)
+}
+
+// getFormatDirective rebuilds the directive of a verb with its flags, width
+// and precision.
+func getFormatDirective(f fmt.State, verb rune) string {
+	var directive strings.Builder
+	directive.WriteRune('%')
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			directive.WriteRune(flag)
+		}
+	}
+
+	if width, ok := f.Width(); ok {
+		directive.WriteString(strconv.Itoa(width))
+	}
+
+	if precision, ok := f.Precision(); ok {
+		directive.WriteString("." + strconv.Itoa(precision))
+	}
+
+	directive.WriteRune(verb)
+	return directive.String()
+}
@@ -0,0 +1,261 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/octopipe/cloudx/internal/lex"
+)
+
+// Node is a parsed expression, Offset is the byte offset of the node in the
+// expression source.
+type Node interface {
+	offset() int
+}
+
+type StringNode struct {
+	Value  string
+	Offset int
+}
+
+type NumberNode struct {
+	Value  float64
+	Offset int
+}
+
+type BoolNode struct {
+	Value  bool
+	Offset int
+}
+
+// PathNode is a reference to external data like this.vpc.id, Origin is the
+// first identifier and Segments the dotted identifiers after it.
+type PathNode struct {
+	Origin   string
+	Segments []string
+	Offset   int
+}
+
+type IndexNode struct {
+	Target Node
+	Index  Node
+	Offset int
+}
+
+type AttrNode struct {
+	Target Node
+	Name   string
+	Offset int
+}
+
+type CallNode struct {
+	Name   string
+	Args   []Node
+	Offset int
+}
+
+type ConcatNode struct {
+	Parts  []Node
+	Offset int
+}
+
+func (n StringNode) offset() int { return n.Offset }
+func (n NumberNode) offset() int { return n.Offset }
+func (n BoolNode) offset() int   { return n.Offset }
+func (n PathNode) offset() int   { return n.Offset }
+func (n IndexNode) offset() int  { return n.Offset }
+func (n AttrNode) offset() int   { return n.Offset }
+func (n CallNode) offset() int   { return n.Offset }
+func (n ConcatNode) offset() int { return n.Offset }
+
+type parser struct {
+	tokens []lex.ExpressionToken
+	pos    int
+}
+
+// Parse parses a single expression, e.g. upper(this.vpc.name) + "-" + default(this.db.port, 5432).
+func Parse(expression string) (Node, error) {
+	tokens, err := lex.TokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	node, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().Type != lex.ExpressionTokenEOF {
+		return nil, p.unexpected()
+	}
+
+	return node, nil
+}
+
+func (p *parser) peek() lex.ExpressionToken {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() lex.ExpressionToken {
+	t := p.tokens[p.pos]
+	if t.Type != lex.ExpressionTokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) expect(tokenType lex.ExpressionTokenType, description string) (lex.ExpressionToken, error) {
+	t := p.peek()
+	if t.Type != tokenType {
+		return t, lex.Error{Offset: t.Offset, Message: fmt.Sprintf("expected %s", description)}
+	}
+
+	return p.next(), nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.Type == lex.ExpressionTokenEOF {
+		return lex.Error{Offset: t.Offset, Message: "unexpected end of expression"}
+	}
+
+	return lex.Error{Offset: t.Offset, Message: fmt.Sprintf("unexpected %q", t.Value)}
+}
+
+func (p *parser) parseConcat() (Node, error) {
+	first, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().Type != lex.ExpressionTokenPlus {
+		return first, nil
+	}
+
+	concat := ConcatNode{Parts: []Node{first}, Offset: first.offset()}
+	for p.peek().Type == lex.ExpressionTokenPlus {
+		p.next()
+		part, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+
+		concat.Parts = append(concat.Parts, part)
+	}
+
+	return concat, nil
+}
+
+func (p *parser) parsePostfix() (Node, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().Type {
+		case lex.ExpressionTokenDot:
+			dot := p.next()
+			name, err := p.expect(lex.ExpressionTokenIdent, "an attribute name after the dot")
+			if err != nil {
+				return nil, err
+			}
+
+			if path, ok := node.(PathNode); ok {
+				path.Segments = append(path.Segments, name.Value)
+				node = path
+				continue
+			}
+
+			node = AttrNode{Target: node, Name: name.Value, Offset: dot.Offset}
+		case lex.ExpressionTokenLeftBracket:
+			bracket := p.next()
+			index, err := p.parseConcat()
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = p.expect(lex.ExpressionTokenRightBracket, "a closing bracket")
+			if err != nil {
+				return nil, err
+			}
+
+			node = IndexNode{Target: node, Index: index, Offset: bracket.Offset}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.peek()
+	switch t.Type {
+	case lex.ExpressionTokenString:
+		p.next()
+		return StringNode{Value: t.Value, Offset: t.Offset}, nil
+	case lex.ExpressionTokenNumber:
+		p.next()
+		value, err := strconv.ParseFloat(t.Value, 64)
+		if err != nil {
+			return nil, lex.Error{Offset: t.Offset, Message: fmt.Sprintf("invalid number %s", t.Value)}
+		}
+
+		return NumberNode{Value: value, Offset: t.Offset}, nil
+	case lex.ExpressionTokenLeftParen:
+		p.next()
+		node, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = p.expect(lex.ExpressionTokenRightParen, "a closing parenthesis")
+		return node, err
+	case lex.ExpressionTokenIdent:
+		p.next()
+		if p.peek().Type == lex.ExpressionTokenLeftParen {
+			return p.parseCall(t)
+		}
+
+		if t.Value == "true" || t.Value == "false" {
+			return BoolNode{Value: t.Value == "true", Offset: t.Offset}, nil
+		}
+
+		return PathNode{Origin: t.Value, Offset: t.Offset}, nil
+	default:
+		return nil, p.unexpected()
+	}
+}
+
+func (p *parser) parseCall(name lex.ExpressionToken) (Node, error) {
+	function, ok := functions[name.Value]
+	if !ok {
+		return nil, lex.Error{Offset: name.Offset, Message: fmt.Sprintf("unknown function %s", name.Value)}
+	}
+
+	p.next()
+	call := CallNode{Name: name.Value, Offset: name.Offset}
+	for p.peek().Type != lex.ExpressionTokenRightParen {
+		if len(call.Args) > 0 {
+			_, err := p.expect(lex.ExpressionTokenComma, "a comma between arguments")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		arg, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+
+		call.Args = append(call.Args, arg)
+	}
+	p.next()
+
+	if len(call.Args) < function.minArgs || (function.maxArgs >= 0 && len(call.Args) > function.maxArgs) {
+		return nil, lex.Error{Offset: name.Offset, Message: fmt.Sprintf("wrong number of arguments for %s, expected %s", name.Value, function.arity())}
+	}
+
+	return call, nil
+}
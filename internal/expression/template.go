@@ -0,0 +1,133 @@
+package expression
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/octopipe/cloudx/internal/lex"
+)
+
+type templatePart struct {
+	text       string
+	expression Node
+	// offset of the part in the template
+	offset int
+}
+
+// Template is a value with expressions between {{ and }}, e.g.
+// "arn:{{ upper(this.vpc.region) }}:{{ this.vpc.subnet_ids[0] }}".
+type Template struct {
+	parts []templatePart
+}
+
+// Reference is a path used by a template, Offset is the byte offset of the
+// path in the template.
+type Reference struct {
+	Origin   string
+	Segments []string
+	Offset   int
+}
+
+// ParseTemplate parses the expressions of a template, error columns are
+// relative to the whole template.
+func ParseTemplate(template string) (Template, error) {
+	tokens, err := lex.Tokenize(template)
+	if err != nil {
+		return Template{}, err
+	}
+
+	t := Template{}
+	for _, token := range tokens {
+		switch token.Type {
+		case lex.TokenText:
+			t.parts = append(t.parts, templatePart{text: token.Value, offset: token.Offset})
+		case lex.TokenVariable:
+			node, err := Parse(token.Value)
+			if err != nil {
+				return Template{}, shiftError(err, token.Offset)
+			}
+
+			t.parts = append(t.parts, templatePart{expression: node, offset: token.Offset})
+		}
+	}
+
+	return t, nil
+}
+
+// Evaluate interpolates the template, the result is sensitive when any of the
+// resolved values is sensitive. Lists and maps are written as JSON.
+func (t Template) Evaluate(resolver Resolver) (string, bool, error) {
+	var result strings.Builder
+	sensitive := false
+	for _, part := range t.parts {
+		if part.expression == nil {
+			result.WriteString(part.text)
+			continue
+		}
+
+		value, err := Evaluate(part.expression, resolver)
+		if err != nil {
+			return "", false, shiftError(err, part.offset)
+		}
+
+		result.WriteString(ToString(value.Data))
+		sensitive = sensitive || value.Sensitive
+	}
+
+	return result.String(), sensitive, nil
+}
+
+// References returns every path used by the template.
+func (t Template) References() []Reference {
+	references := []Reference{}
+	var walk func(node Node, offset int)
+	walk = func(node Node, offset int) {
+		switch n := node.(type) {
+		case PathNode:
+			references = append(references, Reference{Origin: n.Origin, Segments: n.Segments, Offset: offset + n.Offset})
+		case IndexNode:
+			walk(n.Target, offset)
+			walk(n.Index, offset)
+		case AttrNode:
+			walk(n.Target, offset)
+		case CallNode:
+			for _, arg := range n.Args {
+				walk(arg, offset)
+			}
+		case ConcatNode:
+			for _, part := range n.Parts {
+				walk(part, offset)
+			}
+		}
+	}
+
+	for _, part := range t.parts {
+		if part.expression != nil {
+			walk(part.expression, part.offset)
+		}
+	}
+
+	return references
+}
+
+func shiftError(err error, offset int) error {
+	var notFound NotFoundError
+	if errors.As(err, &notFound) {
+		notFound.Offset += offset
+		return notFound
+	}
+
+	var unknown UnknownError
+	if errors.As(err, &unknown) {
+		unknown.Offset += offset
+		return unknown
+	}
+
+	var lexError lex.Error
+	if errors.As(err, &lexError) {
+		lexError.Offset += offset
+		return lexError
+	}
+
+	return lex.Error{Offset: offset, Message: err.Error()}
+}
@@ -0,0 +1,20 @@
+package gitauth
+
+import (
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// NewAuthMethod returns the credentials of the data of an auth secret with the
+// username and password keys, public repositories have no secret data.
+func NewAuthMethod(data map[string][]byte) transport.AuthMethod {
+	if len(data) == 0 {
+		return nil
+	}
+
+	// TODO: SSH AUTHENTICATION
+	return &http.BasicAuth{
+		Username: string(data["username"]),
+		Password: string(data["password"]),
+	}
+}
@@ -1,8 +1,12 @@
 package infra
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/octopipe/cloudx/internal/pagination"
@@ -20,6 +24,8 @@ func NewHTTPHandler(e *gin.Engine, infraUseCase UseCase) *gin.Engine {
 	e.GET("/infra/:shared-infra-name", h.Get)
 	e.PUT("/infra/:shared-infra-name", h.Update)
 	e.PATCH("/infra/:shared-infra-name/reconcile", h.Reconcile)
+	e.PATCH("/infra/:shared-infra-name/plan", h.Plan)
+	e.PATCH("/infra/:shared-infra-name/approve", h.Approve)
 	e.DELETE("/infra/:shared-infra-name", h.Delete)
 
 	return e
@@ -89,7 +95,114 @@ func (h httpHandler) Reconcile(c *gin.Context) {
 	}
 	name := c.Param("shared-infra-name")
 
-	err := h.infraUseCase.Reconcile(c.Request.Context(), name, namespace)
+	options := ReconcileOptions{
+		Mode:   c.Query("mode"),
+		Action: c.Query("action"),
+	}
+
+	if c.Query("targets") != "" {
+		options.Targets = strings.Split(c.Query("targets"), ",")
+	}
+
+	if options.Mode != "" && options.Mode != ForceReconcileMode && options.Mode != DriftCheckReconcileMode && options.Mode != ResumeReconcileMode {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": fmt.Sprintf("invalid reconcile mode %s, use %s, %s or %s", options.Mode, ForceReconcileMode, DriftCheckReconcileMode, ResumeReconcileMode),
+		})
+		return
+	}
+
+	if options.Action != "" && options.Action != ApplyAction && options.Action != DestroyAction {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": fmt.Sprintf("invalid action %s, use %s or %s", options.Action, ApplyAction, DestroyAction),
+		})
+		return
+	}
+
+	if options.Action == DestroyAction && len(options.Targets) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "destroy reconciles require targets, delete the infra to destroy all tasks",
+		})
+		return
+	}
+
+	err := h.infraUseCase.Reconcile(c.Request.Context(), name, namespace, options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h httpHandler) Plan(c *gin.Context) {
+	namespace := "default"
+
+	if c.Query("namespace") != "" {
+		namespace = c.Query("namespace")
+	}
+	name := c.Param("shared-infra-name")
+
+	err := h.infraUseCase.Plan(c.Request.Context(), name, namespace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// getAuthenticatedUser returns the user authenticated by the proxy in front of
+// the API server, read from the header configured in AUTH_USER_HEADER (e.g.
+// X-Forwarded-User). The API server doesn't authenticate its clients, so there
+// isn't a verified user when the header isn't configured.
+func getAuthenticatedUser(c *gin.Context) string {
+	header := os.Getenv("AUTH_USER_HEADER")
+	if header == "" {
+		return ""
+	}
+
+	return c.GetHeader(header)
+}
+
+// Approve approves a task waiting for approval in the running execution, the
+// approver is the user authenticated by the proxy in front of the API server.
+// The proxy must overwrite the AUTH_USER_HEADER header of its clients and be
+// the only way to reach the API server.
+func (h httpHandler) Approve(c *gin.Context) {
+	namespace := "default"
+
+	if c.Query("namespace") != "" {
+		namespace = c.Query("namespace")
+	}
+	name := c.Param("shared-infra-name")
+
+	if c.Query("task") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "task is required to approve a task",
+		})
+		return
+	}
+
+	approver := getAuthenticatedUser(c)
+	if approver == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "approvals require a user authenticated by the proxy in front of the API server, configure AUTH_USER_HEADER with the header of the user",
+		})
+		return
+	}
+
+	err := h.infraUseCase.Approve(c.Request.Context(), name, namespace, c.Query("task"), approver)
+	if errors.Is(err, ErrTaskNotWaitingApproval) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
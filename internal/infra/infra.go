@@ -2,6 +2,7 @@ package infra
 
 import (
 	"context"
+	"errors"
 
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
 	"github.com/octopipe/cloudx/internal/pagination"
@@ -10,18 +11,41 @@ import (
 const (
 	ApplyAction   = "APPLY"
 	DestroyAction = "DESTROY"
+	PlanAction    = "PLAN"
 )
 
+const TaskWaitingApprovalStatus = "WAITING_APPROVAL"
+
+// ErrTaskNotWaitingApproval is returned when approving a task that isn't
+// waiting for approval in the last execution.
+var ErrTaskNotWaitingApproval = errors.New("task is not waiting for approval")
+
+const (
+	ForceReconcileMode      = "force"
+	DriftCheckReconcileMode = "drift-check"
+	ResumeReconcileMode     = "resume"
+)
+
+type ReconcileOptions struct {
+	Mode    string   `json:"mode,omitempty"`
+	Action  string   `json:"action,omitempty"`
+	Targets []string `json:"targets,omitempty"`
+}
+
 type InfraTaskStatus struct {
-	Name        string                           `json:"name"`
-	Depends     []string                         `json:"depends,omitempty"`
-	Backend     string                           `json:"backend"`
-	Inputs      []commonv1alpha1.InfraTaskInput  `json:"inputs"`
-	TaskOutputs []commonv1alpha1.InfraTaskOutput `json:"taskOutputs"`
-	StartedAt   string                           `json:"startedAt,omitempty"`
-	FinishedAt  string                           `json:"finishedAt,omitempty"`
-	Status      string                           `json:"status,omitempty"`
-	Error       commonv1alpha1.Error             `json:"error,omitempty"`
+	Name        string                             `json:"name"`
+	Depends     []string                           `json:"depends,omitempty"`
+	Backend     string                             `json:"backend"`
+	Inputs      []commonv1alpha1.InfraTaskInput    `json:"inputs"`
+	TaskOutputs []commonv1alpha1.InfraTaskOutput   `json:"taskOutputs"`
+	StartedAt   string                             `json:"startedAt,omitempty"`
+	FinishedAt  string                             `json:"finishedAt,omitempty"`
+	Status      string                             `json:"status,omitempty"`
+	Error       commonv1alpha1.Error               `json:"error,omitempty"`
+	Plan        commonv1alpha1.TaskPlanStatus      `json:"plan,omitempty"`
+	Attempts    []commonv1alpha1.TaskAttemptStatus `json:"attempts,omitempty"`
+	Approval    *commonv1alpha1.TaskApprovalStatus `json:"approval,omitempty"`
+	Hooks       []commonv1alpha1.TaskHookStatus    `json:"hooks,omitempty"`
 }
 
 type InfraStatus struct {
@@ -36,6 +60,7 @@ type Infra struct {
 	Name      string      `json:"name"`
 	Namespace string      `json:"namespace"`
 	Status    InfraStatus `json:"status"`
+	LastPlan  InfraStatus `json:"lastPlan"`
 	commonv1alpha1.InfraSpec
 }
 
@@ -44,7 +69,9 @@ type UseCase interface {
 	Create(ctx context.Context, infra Infra) (Infra, error)
 	Update(ctx context.Context, infra Infra) (Infra, error)
 	Get(ctx context.Context, name string, namespace string) (Infra, error)
-	Reconcile(ctx context.Context, name string, namespace string) error
+	Reconcile(ctx context.Context, name string, namespace string, options ReconcileOptions) error
+	Plan(ctx context.Context, name string, namespace string) error
+	Approve(ctx context.Context, name string, namespace string, task string, approver string) error
 	Delete(ctx context.Context, name string, namespace string) error
 }
 
@@ -52,6 +79,8 @@ type Repository interface {
 	List(ctx context.Context, namespace string, chunkPagination pagination.ChunkingPaginationRequest) (commonv1alpha1.InfraList, error)
 	Apply(ctx context.Context, s commonv1alpha1.Infra) (commonv1alpha1.Infra, error)
 	Get(ctx context.Context, name string, namespace string) (commonv1alpha1.Infra, error)
-	Reconcile(ctx context.Context, name string, namespace string) error
+	Reconcile(ctx context.Context, name string, namespace string, options ReconcileOptions) error
+	Plan(ctx context.Context, name string, namespace string) error
+	Approve(ctx context.Context, name string, namespace string, task string, approver string) error
 	Delete(ctx context.Context, name string, namespace string) error
 }
@@ -2,10 +2,14 @@ package infra
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/octopipe/cloudx/apis/common/v1alpha1"
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/annotation"
 	"github.com/octopipe/cloudx/internal/pagination"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
@@ -52,7 +56,7 @@ func (r k8sRepository) Get(ctx context.Context, name string, namespace string) (
 	return infra, err
 }
 
-func (r k8sRepository) Reconcile(ctx context.Context, name string, namespace string) error {
+func (r k8sRepository) Reconcile(ctx context.Context, name string, namespace string, options ReconcileOptions) error {
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 		current := commonv1alpha1.Infra{}
 		err := r.client.Get(ctx, types.NamespacedName{
@@ -63,12 +67,97 @@ func (r k8sRepository) Reconcile(ctx context.Context, name string, namespace str
 			return err
 		}
 
+		annotations := current.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		if options.Mode != "" {
+			annotations[annotation.ReconcileModeAnnotation] = options.Mode
+		}
+
+		if options.Action == DestroyAction {
+			annotations[annotation.ActionAnnotation] = DestroyAction
+		}
+
+		if len(options.Targets) > 0 {
+			annotations[annotation.TargetsAnnotation] = strings.Join(options.Targets, ",")
+		}
+
+		current.SetAnnotations(annotations)
+
+		current.Spec.Generation = uuid.NewString()
+
+		return r.client.Update(ctx, &current)
+	})
+}
+
+func (r k8sRepository) Plan(ctx context.Context, name string, namespace string) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		current := commonv1alpha1.Infra{}
+		err := r.client.Get(ctx, types.NamespacedName{
+			Name:      name,
+			Namespace: namespace,
+		}, &current)
+		if err != nil {
+			return err
+		}
+
+		annotations := current.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[annotation.ActionAnnotation] = PlanAction
+		current.SetAnnotations(annotations)
 		current.Spec.Generation = uuid.NewString()
 
 		return r.client.Update(ctx, &current)
 	})
 }
 
+// Approve gives the approval to a task waiting for it in the last execution,
+// the runner polls the approvals of the infra status to resume the execution.
+func (r k8sRepository) Approve(ctx context.Context, name string, namespace string, task string, approver string) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		current := commonv1alpha1.Infra{}
+		err := r.client.Get(ctx, types.NamespacedName{
+			Name:      name,
+			Namespace: namespace,
+		}, &current)
+		if err != nil {
+			return err
+		}
+
+		isWaiting := false
+		for _, t := range current.Status.LastExecution.Tasks {
+			if t.Name == task && t.Status == TaskWaitingApprovalStatus {
+				isWaiting = true
+				break
+			}
+		}
+
+		if !isWaiting {
+			return fmt.Errorf("%w: %s", ErrTaskNotWaitingApproval, task)
+		}
+
+		approvals := []commonv1alpha1.TaskApproval{}
+		for _, a := range current.Status.Approvals {
+			if a.Task != task {
+				approvals = append(approvals, a)
+			}
+		}
+
+		current.Status.Approvals = append(approvals, commonv1alpha1.TaskApproval{
+			Task:       task,
+			Approver:   approver,
+			ApprovedAt: time.Now().Format(time.RFC3339),
+		})
+
+		return r.client.Status().Update(ctx, &current)
+	})
+}
+
 // Delete implements Repository.
 func (r k8sRepository) Delete(ctx context.Context, name string, namespace string) error {
 	infra, err := r.Get(ctx, name, namespace)
@@ -40,6 +40,13 @@ func (u useCase) Create(ctx context.Context, infra Infra) (Infra, error) {
 			Error:      s.Status.LastExecution.Error,
 			Tasks:      maskTasksSensitiveData(s.Status.LastExecution.Tasks),
 		},
+		LastPlan: InfraStatus{
+			StartedAt:  s.Status.LastPlan.StartedAt,
+			FinishedAt: s.Status.LastPlan.FinishedAt,
+			Status:     s.Status.LastPlan.Status,
+			Error:      s.Status.LastPlan.Error,
+			Tasks:      maskTasksSensitiveData(s.Status.LastPlan.Tasks),
+		},
 	}, nil
 }
 
@@ -66,11 +73,26 @@ func (u useCase) Get(ctx context.Context, name string, namespace string) (Infra,
 			Error:      s.Status.LastExecution.Error,
 			Tasks:      maskTasksSensitiveData(s.Status.LastExecution.Tasks),
 		},
+		LastPlan: InfraStatus{
+			StartedAt:  s.Status.LastPlan.StartedAt,
+			FinishedAt: s.Status.LastPlan.FinishedAt,
+			Status:     s.Status.LastPlan.Status,
+			Error:      s.Status.LastPlan.Error,
+			Tasks:      maskTasksSensitiveData(s.Status.LastPlan.Tasks),
+		},
 	}, nil
 }
 
-func (u useCase) Reconcile(ctx context.Context, name string, namespace string) error {
-	return u.repository.Reconcile(ctx, name, namespace)
+func (u useCase) Reconcile(ctx context.Context, name string, namespace string, options ReconcileOptions) error {
+	return u.repository.Reconcile(ctx, name, namespace, options)
+}
+
+func (u useCase) Plan(ctx context.Context, name string, namespace string) error {
+	return u.repository.Plan(ctx, name, namespace)
+}
+
+func (u useCase) Approve(ctx context.Context, name string, namespace string, task string, approver string) error {
+	return u.repository.Approve(ctx, name, namespace, task, approver)
 }
 
 // List implements UseCase.
@@ -93,6 +115,13 @@ func (u useCase) List(ctx context.Context, namespace string, chunkPagination pag
 				Error:      i.Status.LastExecution.Error,
 				Tasks:      maskTasksSensitiveData(i.Status.LastExecution.Tasks),
 			},
+			LastPlan: InfraStatus{
+				StartedAt:  i.Status.LastPlan.StartedAt,
+				FinishedAt: i.Status.LastPlan.FinishedAt,
+				Status:     i.Status.LastPlan.Status,
+				Error:      i.Status.LastPlan.Error,
+				Tasks:      maskTasksSensitiveData(i.Status.LastPlan.Tasks),
+			},
 		})
 	}
 
@@ -127,6 +156,13 @@ func (u useCase) Update(ctx context.Context, infra Infra) (Infra, error) {
 			Error:      s.Status.LastExecution.Error,
 			Tasks:      maskTasksSensitiveData(s.Status.LastExecution.Tasks),
 		},
+		LastPlan: InfraStatus{
+			StartedAt:  s.Status.LastPlan.StartedAt,
+			FinishedAt: s.Status.LastPlan.FinishedAt,
+			Status:     s.Status.LastPlan.Status,
+			Error:      s.Status.LastPlan.Error,
+			Tasks:      maskTasksSensitiveData(s.Status.LastPlan.Tasks),
+		},
 	}, nil
 }
 
@@ -153,6 +189,10 @@ func maskTasksSensitiveData(taskStatus []commonv1alpha1.TaskExecutionStatus) []I
 			Status:      p.Status,
 			Error:       p.Error,
 			TaskOutputs: p.TaskOutputs,
+			Plan:        p.Plan,
+			Attempts:    p.Attempts,
+			Approval:    p.Approval,
+			Hooks:       p.Hooks,
 		})
 	}
 
@@ -0,0 +1,159 @@
+package lex
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ExpressionTokenType int
+
+const (
+	ExpressionTokenEOF ExpressionTokenType = iota
+	ExpressionTokenIdent
+	ExpressionTokenString
+	ExpressionTokenNumber
+	ExpressionTokenDot
+	ExpressionTokenComma
+	ExpressionTokenPlus
+	ExpressionTokenLeftParen
+	ExpressionTokenRightParen
+	ExpressionTokenLeftBracket
+	ExpressionTokenRightBracket
+)
+
+// ExpressionToken is a token of an expression written between delimiters,
+// Offset is the byte offset of the token in the expression.
+type ExpressionToken struct {
+	Type   ExpressionTokenType
+	Value  string
+	Offset int
+}
+
+// Error is a malformed template or expression, Offset is the byte offset where
+// the problem was found.
+type Error struct {
+	Offset  int
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Offset+1, e.Message)
+}
+
+var expressionPunctuation = map[byte]ExpressionTokenType{
+	'.': ExpressionTokenDot,
+	',': ExpressionTokenComma,
+	'+': ExpressionTokenPlus,
+	'(': ExpressionTokenLeftParen,
+	')': ExpressionTokenRightParen,
+	'[': ExpressionTokenLeftBracket,
+	']': ExpressionTokenRightBracket,
+}
+
+// TokenizeExpression splits an expression in identifiers, literals and
+// punctuation. Identifiers may contain dashes and slashes, so task names like
+// demo-0-sns and namespaced names like team-a/vpc are a single token.
+func TokenizeExpression(expression string) ([]ExpressionToken, error) {
+	tokens := []ExpressionToken{}
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			value, end, err := scanString(expression, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, ExpressionToken{Type: ExpressionTokenString, Value: value, Offset: i})
+			i = end
+		case isIdentChar(c):
+			start := i
+			for i < len(expression) && isIdentChar(expression[i]) {
+				i++
+			}
+
+			tokenType := ExpressionTokenIdent
+			word := expression[start:i]
+			if isNumber(word) {
+				tokenType = ExpressionTokenNumber
+				// decimal numbers are split by the dot punctuation
+				if i+1 < len(expression) && expression[i] == '.' && isDigit(expression[i+1]) {
+					i++
+					for i < len(expression) && isDigit(expression[i]) {
+						i++
+					}
+					word = expression[start:i]
+				}
+			}
+
+			tokens = append(tokens, ExpressionToken{Type: tokenType, Value: word, Offset: start})
+		default:
+			tokenType, ok := expressionPunctuation[c]
+			if !ok {
+				return nil, Error{Offset: i, Message: fmt.Sprintf("unexpected character %q", c)}
+			}
+
+			tokens = append(tokens, ExpressionToken{Type: tokenType, Value: string(c), Offset: i})
+			i++
+		}
+	}
+
+	tokens = append(tokens, ExpressionToken{Type: ExpressionTokenEOF, Offset: len(expression)})
+	return tokens, nil
+}
+
+// scanString reads a quoted string starting at start, returning its unescaped
+// value and the offset after the closing quote.
+func scanString(expression string, start int) (string, int, error) {
+	quote := expression[start]
+	var value strings.Builder
+	for i := start + 1; i < len(expression); i++ {
+		c := expression[i]
+		if c == quote {
+			return value.String(), i + 1, nil
+		}
+
+		if c != '\\' {
+			value.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(expression) {
+			break
+		}
+
+		switch expression[i] {
+		case 'n':
+			value.WriteByte('\n')
+		case 't':
+			value.WriteByte('\t')
+		case '\\', '"', '\'':
+			value.WriteByte(expression[i])
+		default:
+			return "", 0, Error{Offset: i - 1, Message: fmt.Sprintf("invalid escape sequence \\%c", expression[i])}
+		}
+	}
+
+	return "", 0, Error{Offset: start, Message: "unterminated string"}
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '-' || c == '/' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNumber(word string) bool {
+	for i := 0; i < len(word); i++ {
+		if !isDigit(word[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
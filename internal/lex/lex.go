@@ -12,60 +12,153 @@ const (
 	TokenDelimiter
 )
 
+const (
+	OpenDelimiter  = "{{"
+	CloseDelimiter = "}}"
+	// EscapedOpenDelimiter is written for a literal {{, e.g. for helm values
+	// passed to terraform. A }} outside a variable is always literal.
+	EscapedOpenDelimiter = `\{{`
+)
+
+// Token is a piece of a template, Offset is the byte offset of the token in
+// the template. The Value of text tokens is unescaped and the Value of variable
+// tokens is the expression between the delimiters.
 type Token struct {
-	Type  TokenType
-	Value string
+	Type   TokenType
+	Value  string
+	Offset int
 }
 
-func Tokenize(template string) []Token {
-	var tokens []Token
-	var currentToken strings.Builder
+// Tokenize splits a template in text, delimiters and the variables between
+// them. Unclosed {{, nested {{ and empty variables are errors, a }} without a
+// matching {{ is literal text (e.g. the end of a nested JSON object).
+func Tokenize(template string) ([]Token, error) {
+	s := &scanner{template: template}
+	for s.pos < len(template) {
+		var err error
+		if s.inside {
+			err = s.scanVariable()
+		} else {
+			err = s.scanText()
+		}
 
-	remaining := template
-	openDelimiter := "{{"
-	closeDelimiter := "}}"
-	openDelimiterLen := len(openDelimiter)
-	closeDelimiterLen := len(closeDelimiter)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	for len(remaining) > 0 {
-		openIndex := strings.Index(remaining, openDelimiter)
-		closeIndex := strings.Index(remaining, closeDelimiter)
+	s.flushText()
+	return s.tokens, nil
+}
 
-		if openIndex == -1 && closeIndex == -1 {
-			currentToken.WriteString(remaining)
-			break
-		}
+type scanner struct {
+	template  string
+	pos       int
+	inside    bool
+	tokens    []Token
+	text      strings.Builder
+	textStart int
+}
 
-		if openIndex != -1 && (openIndex < closeIndex || closeIndex == -1) {
-			if openIndex > 0 {
+func (s *scanner) flushText() {
+	if s.text.Len() > 0 {
+		s.tokens = append(s.tokens, Token{Type: TokenText, Value: s.text.String(), Offset: s.textStart})
+		s.text.Reset()
+	}
+}
 
-				currentToken.WriteString(remaining[:openIndex])
-				tokens = append(tokens, Token{Type: TokenVariable, Value: currentToken.String()})
-				currentToken.Reset()
-			}
+func (s *scanner) writeText(value string, size int) {
+	if s.text.Len() == 0 {
+		s.textStart = s.pos
+	}
+
+	s.text.WriteString(value)
+	s.pos += size
+}
 
-			tokens = append(tokens, Token{Type: TokenDelimiter, Value: openDelimiter})
-			remaining = remaining[openIndex+openDelimiterLen:]
-		} else if closeIndex != -1 && (closeIndex < openIndex || openIndex == -1) {
+func (s *scanner) scanText() error {
+	remaining := s.template[s.pos:]
+	switch {
+	case strings.HasPrefix(remaining, EscapedOpenDelimiter):
+		s.writeText(OpenDelimiter, len(EscapedOpenDelimiter))
+	case strings.HasPrefix(remaining, OpenDelimiter):
+		s.flushText()
+		s.tokens = append(s.tokens, Token{Type: TokenDelimiter, Value: OpenDelimiter, Offset: s.pos})
+		s.pos += len(OpenDelimiter)
+		s.inside = true
+	default:
+		s.writeText(remaining[:1], 1)
+	}
+
+	return nil
+}
+
+// scanVariable reads the expression until the closing delimiter, delimiters
+// inside quoted strings are part of the expression.
+func (s *scanner) scanVariable() error {
+	openOffset := s.pos - len(OpenDelimiter)
+	start := s.pos
+	var quote byte
+	quoteOffset := 0
+	for i := start; i < len(s.template); i++ {
+		c := s.template[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
 
-			if closeIndex > 0 {
-				currentToken.WriteString(remaining[:closeIndex])
-				tokens = append(tokens, Token{Type: TokenVariable, Value: currentToken.String()})
-				currentToken.Reset()
+		remaining := s.template[i:]
+		switch {
+		case c == '"' || c == '\'':
+			quote = c
+			quoteOffset = i
+		case strings.HasPrefix(remaining, OpenDelimiter):
+			return Error{Offset: i, Message: "unexpected {{ inside a variable"}
+		case strings.HasPrefix(remaining, CloseDelimiter):
+			value := s.template[start:i]
+			if strings.TrimSpace(value) == "" {
+				return Error{Offset: openOffset, Message: "empty variable"}
 			}
 
-			tokens = append(tokens, Token{Type: TokenDelimiter, Value: closeDelimiter})
-			remaining = remaining[closeIndex+closeDelimiterLen:]
+			s.tokens = append(s.tokens,
+				Token{Type: TokenVariable, Value: value, Offset: start},
+				Token{Type: TokenDelimiter, Value: CloseDelimiter, Offset: i},
+			)
+			s.pos = i + len(CloseDelimiter)
+			s.inside = false
+			return nil
 		}
 	}
 
-	if currentToken.Len() > 0 {
-		tokens = append(tokens, Token{Type: TokenText, Value: currentToken.String()})
+	if quote != 0 {
+		return Error{Offset: quoteOffset, Message: "unterminated string"}
 	}
 
-	return tokens
+	return Error{Offset: openOffset, Message: "unclosed {{"}
+}
+
+// Render writes the tokens back as a template, escaping the literal {{ of
+// text tokens. Render(Tokenize(template)) returns the template.
+func Render(tokens []Token) string {
+	var result strings.Builder
+	for _, token := range tokens {
+		if token.Type == TokenText {
+			result.WriteString(strings.ReplaceAll(token.Value, OpenDelimiter, EscapedOpenDelimiter))
+			continue
+		}
+
+		result.WriteString(token.Value)
+	}
+
+	return result.String()
 }
 
+// Interpolate replaces the variables by their data, variables without data
+// are removed.
 func Interpolate(tokens []Token, data map[string]string) string {
 	var result strings.Builder
 
@@ -0,0 +1,127 @@
+package lex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LexTestSuite struct {
+	suite.Suite
+}
+
+func (suite *LexTestSuite) TestTokenize() {
+	tokens, err := Tokenize("arn:{{ this.vpc.id }}/{{this.vpc.name}}")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []Token{
+		{Type: TokenText, Value: "arn:", Offset: 0},
+		{Type: TokenDelimiter, Value: "{{", Offset: 4},
+		{Type: TokenVariable, Value: " this.vpc.id ", Offset: 6},
+		{Type: TokenDelimiter, Value: "}}", Offset: 19},
+		{Type: TokenText, Value: "/", Offset: 21},
+		{Type: TokenDelimiter, Value: "{{", Offset: 22},
+		{Type: TokenVariable, Value: "this.vpc.name", Offset: 24},
+		{Type: TokenDelimiter, Value: "}}", Offset: 37},
+	}, tokens)
+}
+
+func (suite *LexTestSuite) TestEscapedDelimiter() {
+	tokens, err := Tokenize(`image: \{{ .Values.image }}:{{ this.app.tag }}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []Token{
+		{Type: TokenText, Value: "image: {{ .Values.image }}:", Offset: 0},
+		{Type: TokenDelimiter, Value: "{{", Offset: 28},
+		{Type: TokenVariable, Value: " this.app.tag ", Offset: 30},
+		{Type: TokenDelimiter, Value: "}}", Offset: 44},
+	}, tokens)
+	assert.Equal(suite.T(), "image: {{ .Values.image }}:v1", Interpolate(tokens, map[string]string{" this.app.tag ": "v1"}))
+}
+
+func (suite *LexTestSuite) TestDelimiterInsideString() {
+	tokens, err := Tokenize(`{{ default(this.a.b, "}}") }}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), Token{Type: TokenVariable, Value: ` default(this.a.b, "}}") `, Offset: 2}, tokens[1])
+}
+
+func (suite *LexTestSuite) TestUnmatchedCloseDelimiter() {
+	tokens, err := Tokenize(`{"tags":{"env":"{{ this.env.name }}"}}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []Token{
+		{Type: TokenText, Value: `{"tags":{"env":"`, Offset: 0},
+		{Type: TokenDelimiter, Value: "{{", Offset: 16},
+		{Type: TokenVariable, Value: " this.env.name ", Offset: 18},
+		{Type: TokenDelimiter, Value: "}}", Offset: 33},
+		{Type: TokenText, Value: `"}}`, Offset: 35},
+	}, tokens)
+
+	tokens, err = Tokenize(`{"tags":{"env":"dev"}}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []Token{{Type: TokenText, Value: `{"tags":{"env":"dev"}}`, Offset: 0}}, tokens)
+}
+
+func (suite *LexTestSuite) TestMalformedTemplates() {
+	cases := map[string]string{
+		"abc {{ this.a.b":        "column 5: unclosed {{",
+		"{{ this.a {{ this.b }}": "column 11: unexpected {{ inside a variable",
+		"x{{  }}":                "column 2: empty variable",
+		`{{ upper("abc) }}`:      "column 10: unterminated string",
+	}
+
+	for template, expected := range cases {
+		_, err := Tokenize(template)
+		if assert.Error(suite.T(), err, template) {
+			assert.Equal(suite.T(), expected, err.Error(), template)
+		}
+	}
+}
+
+func (suite *LexTestSuite) TestRoundTrip() {
+	templates := []string{
+		"",
+		"plain text",
+		"{{ this.vpc.id }}",
+		"prefix-{{this.vpc.id}}-{{ task-output.shared.name }}-suffix",
+		`\{{ .Values.name }}`,
+		`a \{{ b }} {{ this.c.d }} \{{ e }}`,
+		`\\{{ literal }}`,
+		`back\slash {{ format("%s}}", this.a.b) }}`,
+		"unicode ✓ {{ this.a.b }} ✓",
+		"{ single } braces {{ this.a.b }}",
+		`{"a":{"b":"{{ this.c.d }}"}} }}`,
+	}
+
+	for _, template := range templates {
+		tokens, err := Tokenize(template)
+		if assert.NoError(suite.T(), err, template) {
+			assert.Equal(suite.T(), template, Render(tokens), template)
+		}
+	}
+}
+
+func (suite *LexTestSuite) TestTokenizeExpression() {
+	tokens, err := TokenizeExpression(`join(", ", this.vpc-a.ids[0]) + 'x\'y' + 1.5`)
+	assert.NoError(suite.T(), err)
+
+	types := []ExpressionTokenType{}
+	for _, t := range tokens {
+		types = append(types, t.Type)
+	}
+
+	assert.Equal(suite.T(), []ExpressionTokenType{
+		ExpressionTokenIdent, ExpressionTokenLeftParen, ExpressionTokenString, ExpressionTokenComma,
+		ExpressionTokenIdent, ExpressionTokenDot, ExpressionTokenIdent, ExpressionTokenDot, ExpressionTokenIdent,
+		ExpressionTokenLeftBracket, ExpressionTokenNumber, ExpressionTokenRightBracket, ExpressionTokenRightParen,
+		ExpressionTokenPlus, ExpressionTokenString, ExpressionTokenPlus, ExpressionTokenNumber, ExpressionTokenEOF,
+	}, types)
+	assert.Equal(suite.T(), ExpressionToken{Type: ExpressionTokenIdent, Value: "vpc-a", Offset: 16}, tokens[6])
+	assert.Equal(suite.T(), "x'y", tokens[14].Value)
+	assert.Equal(suite.T(), "1.5", tokens[16].Value)
+
+	_, err = TokenizeExpression("this.a.b * 2")
+	assert.EqualError(suite.T(), err, "column 10: unexpected character '*'")
+}
+
+func TestLexTestSuite(t *testing.T) {
+	suite.Run(t, new(LexTestSuite))
+}
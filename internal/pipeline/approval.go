@@ -0,0 +1,144 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/approval"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var approvalPollInterval = 5 * time.Second
+
+// waitTaskApproval publishes the task as waiting for approval, with the plans
+// of the task and its dependents, and blocks until the task is approved.
+func (p *pipelineCtx) waitTaskApproval(ctx context.Context, infra commonv1alpha1.Infra, waitingStatus commonv1alpha1.TaskExecutionStatus, executionContext ExecutionContext) (*commonv1alpha1.TaskApprovalStatus, error) {
+	p.logger.Info("waiting task approval", zap.String("name", waitingStatus.Name))
+	plans := p.getApprovalPlans(ctx, infra, waitingStatus.Name, executionContext)
+	waitingStatus.Status = TaskWaitingApprovalStatus
+	waitingStatus.Approval = &commonv1alpha1.TaskApprovalStatus{Plans: plans}
+	p.notifyWaitingApproval(waitingStatus)
+
+	for {
+		taskApproval := commonv1alpha1.TaskApproval{}
+		err := p.rpcClient.Call("ApprovalRPCHandler.GetTaskApproval", approval.RPCGetTaskApprovalArgs{
+			Ref:  types.NamespacedName{Name: infra.Name, Namespace: infra.Namespace},
+			Task: waitingStatus.Name,
+		}, &taskApproval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the approval of task %s: %w", waitingStatus.Name, err)
+		}
+
+		if taskApproval.Approver != "" {
+			p.logger.Info("task approved", zap.String("name", waitingStatus.Name), zap.String("approver", taskApproval.Approver))
+			return &commonv1alpha1.TaskApprovalStatus{
+				Approver:   taskApproval.Approver,
+				ApprovedAt: taskApproval.ApprovedAt,
+				Plans:      plans,
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(approvalPollInterval):
+		}
+	}
+}
+
+// getApprovalPlans plans the task waiting for approval and its dependents in
+// dependency order, dependents of tasks still running elsewhere in the graph
+// can't be interpolated and keep the error of their plan.
+func (p *pipelineCtx) getApprovalPlans(ctx context.Context, infra commonv1alpha1.Infra, taskName string, executionContext ExecutionContext) []commonv1alpha1.TaskApprovalPlan {
+	graph := p.getApplyGraph(infra)
+	pending := map[string]bool{taskName: true}
+	for _, dependent := range getTransitiveDependents(graph, taskName) {
+		pending[dependent] = true
+	}
+
+	planContext := ExecutionContext{}
+	for node, outputs := range executionContext {
+		planContext[node] = outputs
+	}
+
+	plan := p.plan(infra, nil)
+	plans := []commonv1alpha1.TaskApprovalPlan{}
+	for len(pending) > 0 {
+		ready := []string{}
+		for node := range pending {
+			isReady := true
+			for _, dep := range graph[node] {
+				if pending[dep] {
+					isReady = false
+					break
+				}
+			}
+
+			if isReady {
+				ready = append(ready, node)
+			}
+		}
+		sort.Strings(ready)
+
+		for _, node := range ready {
+			delete(pending, node)
+			status, outputs := plan(ctx, node, planContext)
+			planContext[node] = outputs
+			plans = append(plans, commonv1alpha1.TaskApprovalPlan{
+				Name:  node,
+				Plan:  status.Plan,
+				Error: status.Error,
+			})
+		}
+	}
+
+	return plans
+}
+
+// notifyWaitingApproval publishes a task waiting for approval through the
+// running execution.
+func (p *pipelineCtx) notifyWaitingApproval(status commonv1alpha1.TaskExecutionStatus) {
+	if p.waitingApprovalChan == nil {
+		return
+	}
+
+	p.waitingApprovalChan <- status
+}
+
+// getWaitingApprovalStatus adds the tasks waiting for approval to the status
+// published while the execution is paused.
+func getWaitingApprovalStatus(status commonv1alpha1.ExecutionStatus, waitingTasks map[string]commonv1alpha1.TaskExecutionStatus) commonv1alpha1.ExecutionStatus {
+	names := []string{}
+	for name := range waitingTasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	status.Tasks = append([]commonv1alpha1.TaskExecutionStatus{}, status.Tasks...)
+	for _, name := range names {
+		status.Tasks = append(status.Tasks, waitingTasks[name])
+	}
+	status.Status = InfraWaitingApprovalStatus
+
+	return status
+}
+
+func getTaskApprovalError(ctx context.Context, taskName string, err error) (commonv1alpha1.Error, string) {
+	if ctx.Err() == context.DeadlineExceeded {
+		return commonv1alpha1.Error{
+			Message: fmt.Sprintf("time limit exceeded waiting the approval of task %s", taskName),
+			Code:    "TASK_APPROVAL_TIME_LIMIT_EXCEEDED",
+			Tip:     "Approve the task before the execution timeout or increase it",
+		}, TaskTimeoutStatus
+	}
+
+	return commonv1alpha1.Error{
+		Message: err.Error(),
+		Code:    "TASK_APPROVAL_ERROR",
+		Tip:     fmt.Sprintf("Verify that the controller is reachable and reconcile the infra to approve the task %s again", taskName),
+	}, TaskApplyErrorStatus
+}
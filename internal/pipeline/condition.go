@@ -0,0 +1,71 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"go.uber.org/zap"
+)
+
+// evaluateTaskCondition interpolates the when expression of the task, tasks
+// without condition are always executed.
+func (p *pipelineCtx) evaluateTaskCondition(currentTask commonv1alpha1.InfraTask, executionContext ExecutionContext) (bool, error) {
+	if currentTask.When == "" {
+		return true, nil
+	}
+
+	value, _, err := p.interpolateValue(currentTask.Name, "when", currentTask.When, executionContext)
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true":
+		return true, nil
+	case "false", "":
+		return false, nil
+	default:
+		return false, fmt.Errorf("the condition of task %s must evaluate to true or false, got %s", currentTask.Name, value)
+	}
+}
+
+func getTaskConditionError(taskName string, err error) commonv1alpha1.Error {
+	return commonv1alpha1.Error{
+		Message: err.Error(),
+		Code:    "TASK_CONDITION_ERROR",
+		Tip:     fmt.Sprintf("Verify that the when expression of task %s is valid", taskName),
+	}
+}
+
+// skipTaskByCondition destroys the resources of a task whose condition is
+// false, tasks that never existed are simply not created.
+func (p *pipelineCtx) skipTaskByCondition(ctx context.Context, infra commonv1alpha1.Infra, currentTask commonv1alpha1.InfraTask, lastTaskExecutionStatus commonv1alpha1.TaskExecutionStatus) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+	p.logger.Info("skipping task by condition", zap.String("name", currentTask.Name))
+	status := commonv1alpha1.TaskExecutionStatus{
+		Name:        currentTask.Name,
+		Depends:     currentTask.Depends,
+		Inputs:      currentTask.Inputs,
+		Backend:     currentTask.Backend,
+		TaskOutputs: currentTask.TaskOutputs,
+		StartedAt:   time.Now().Format(time.RFC3339),
+	}
+
+	if lastTaskExecutionStatus.Task.State != "" {
+		p.logger.Info("destroying task disabled by condition", zap.String("name", currentTask.Name))
+		destroyStatus, _ := p.destroy(infra)(ctx, currentTask.Name, ExecutionContext{})
+		if isTaskFailed(destroyStatus) {
+			destroyStatus.Depends = currentTask.Depends
+			return destroyStatus, nil
+		}
+
+		status.Attempts = destroyStatus.Attempts
+		status.Task.ArchivedState = destroyStatus.Task.ArchivedState
+	}
+
+	status.Status = TaskConditionSkippedStatus
+	status.FinishedAt = time.Now().Format(time.RFC3339)
+	return status, map[string]ExecutionOutputItem{}
+}
@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/statearchive"
+	"go.uber.org/zap"
+)
+
+const (
+	DeleteDeletionPolicy = "Delete"
+	RetainDeletionPolicy = "Retain"
+)
+
+// getDeletionPolicy returns the policy of the spec for tasks that are still in
+// the spec, removed tasks use the policy of their last apply.
+func getDeletionPolicy(infra commonv1alpha1.Infra, lastTaskExecutionStatus commonv1alpha1.TaskExecutionStatus) string {
+	policy := lastTaskExecutionStatus.Task.DeletionPolicy
+	for _, t := range infra.Spec.Tasks {
+		if t.Name == lastTaskExecutionStatus.Name {
+			policy = t.DeletionPolicy
+			break
+		}
+	}
+
+	if policy == "" {
+		return DeleteDeletionPolicy
+	}
+
+	return policy
+}
+
+// retain releases a task from cloudx without destroying its resources, the
+// last state is archived so the resources can be imported or destroyed later.
+func (p *pipelineCtx) retain(infra commonv1alpha1.Infra, lastTaskExecutionStatus commonv1alpha1.TaskExecutionStatus) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+	p.logger.Info("retaining task", zap.String("name", lastTaskExecutionStatus.Name))
+	status := commonv1alpha1.TaskExecutionStatus{
+		Name:        lastTaskExecutionStatus.Name,
+		Depends:     lastTaskExecutionStatus.Depends,
+		Backend:     lastTaskExecutionStatus.Backend,
+		Inputs:      lastTaskExecutionStatus.Inputs,
+		TaskOutputs: lastTaskExecutionStatus.TaskOutputs,
+		Task:        lastTaskExecutionStatus.Task,
+		Status:      TaskRetainedStatus,
+		StartedAt:   time.Now().Format(time.RFC3339),
+	}
+
+	if lastTaskExecutionStatus.Task.State != "" {
+		archivedState := commonv1alpha1.Ref{}
+		err := p.rpcClient.Call("StateArchiveRPCHandler.ArchiveState", statearchive.RPCArchiveStateArgs{
+			InfraRef: commonv1alpha1.Ref{Name: infra.Name, Namespace: infra.Namespace},
+			TaskName: lastTaskExecutionStatus.Name,
+			Task:     lastTaskExecutionStatus.Task,
+		}, &archivedState)
+		if err != nil {
+			status.Error = commonv1alpha1.Error{
+				Message: err.Error(),
+				Code:    "TASK_STATE_ARCHIVE_ERROR",
+				Tip:     fmt.Sprintf("The resources of task %s were not destroyed, retry the execution to archive its state", lastTaskExecutionStatus.Name),
+			}
+			status.Status = TaskDestroyErrorStatus
+			return status, nil
+		}
+
+		status.Task.ArchivedState = &archivedState
+	}
+
+	status.FinishedAt = time.Now().Format(time.RFC3339)
+	return status, nil
+}
+
+// planRelease plans the destroy of a task, tasks with the Retain policy are
+// only released and have nothing to plan.
+func (p *pipelineCtx) planRelease(ctx context.Context, infra commonv1alpha1.Infra, lastTaskExecutionStatus commonv1alpha1.TaskExecutionStatus) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+	if getDeletionPolicy(infra, lastTaskExecutionStatus) != RetainDeletionPolicy {
+		return p.planDestroy(ctx, lastTaskExecutionStatus)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	return commonv1alpha1.TaskExecutionStatus{
+		Name:        lastTaskExecutionStatus.Name,
+		Depends:     lastTaskExecutionStatus.Depends,
+		Inputs:      lastTaskExecutionStatus.Inputs,
+		Backend:     lastTaskExecutionStatus.Backend,
+		TaskOutputs: lastTaskExecutionStatus.TaskOutputs,
+		Task:        commonv1alpha1.TaskStatus{Terraform: lastTaskExecutionStatus.Task.Terraform},
+		Status:      TaskRetainedStatus,
+		StartedAt:   now,
+		FinishedAt:  now,
+	}, map[string]ExecutionOutputItem{}
+}
+
+func isValidDeletionPolicy(policy string) bool {
+	return policy == "" || policy == DeleteDeletionPolicy || policy == RetainDeletionPolicy
+}
@@ -0,0 +1,145 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/backend/terraform"
+	"go.uber.org/zap"
+)
+
+type taskFingerprint struct {
+	SourceDigest    string                                    `json:"sourceDigest"`
+	Version         string                                    `json:"version"`
+	Inputs          []commonv1alpha1.InfraTaskInput           `json:"inputs"`
+	TaskOutputs     []commonv1alpha1.InfraTaskOutput          `json:"taskOutputs"`
+	UpstreamOutputs map[string]map[string]ExecutionOutputItem `json:"upstreamOutputs"`
+}
+
+// getTaskFingerprint hashes everything that can change the result of a task
+// apply, two applies with the same fingerprint produce the same infrastructure.
+func getTaskFingerprint(sourceDigest string, currentTask commonv1alpha1.InfraTask, inputs []commonv1alpha1.InfraTaskInput, executionContext ExecutionContext) (string, error) {
+	sortedInputs := append([]commonv1alpha1.InfraTaskInput{}, inputs...)
+	sort.Slice(sortedInputs, func(i, j int) bool {
+		return sortedInputs[i].Key < sortedInputs[j].Key
+	})
+
+	upstreamOutputs := map[string]map[string]ExecutionOutputItem{}
+	for _, dep := range currentTask.Depends {
+		outputs := map[string]ExecutionOutputItem{}
+		for key, output := range executionContext[dep] {
+			output.Value = normalizeOutputValue(output.Value)
+			output.Type = normalizeOutputValue(output.Type)
+			outputs[key] = output
+		}
+		upstreamOutputs[dep] = outputs
+	}
+
+	rawFingerprint, err := json.Marshal(taskFingerprint{
+		SourceDigest:    sourceDigest,
+		Version:         currentTask.Terraform.Version,
+		Inputs:          sortedInputs,
+		TaskOutputs:     currentTask.TaskOutputs,
+		UpstreamOutputs: upstreamOutputs,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(rawFingerprint)), nil
+}
+
+// normalizeOutputValue removes the formatting differences between the outputs
+// read from terraform and the ones read from a persisted state.
+func normalizeOutputValue(value string) string {
+	var parsed interface{}
+	err := json.Unmarshal([]byte(value), &parsed)
+	if err != nil {
+		return value
+	}
+
+	normalized, err := json.Marshal(parsed)
+	if err != nil {
+		return value
+	}
+
+	return string(normalized)
+}
+
+func isTaskUnchanged(lastTaskExecutionStatus commonv1alpha1.TaskExecutionStatus, fingerprint string) bool {
+	if lastTaskExecutionStatus.Status != TaskAppliedStatus && lastTaskExecutionStatus.Status != TaskUnchangedStatus {
+		return false
+	}
+
+	return lastTaskExecutionStatus.Task.Fingerprint != "" && lastTaskExecutionStatus.Task.Fingerprint == fingerprint
+}
+
+func getOutputsFromState(state string) (map[string]ExecutionOutputItem, error) {
+	stateOutputs, err := terraform.GetStateOutputs(state)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]ExecutionOutputItem{}
+	for key, tfMeta := range stateOutputs {
+		outputs[key] = ExecutionOutputItem{
+			Value:     string(tfMeta.Value),
+			Type:      string(tfMeta.Type),
+			Sensitive: tfMeta.Sensitive,
+		}
+	}
+
+	return outputs, nil
+}
+
+// getUnchangedTaskStatus reuses the last execution of a task with the same
+// fingerprint, on drift-check reconciles the task is only reused when terraform
+// plans no changes.
+func (p *pipelineCtx) getUnchangedTaskStatus(ctx context.Context, currentTask commonv1alpha1.InfraTask, lastTaskExecutionStatus commonv1alpha1.TaskExecutionStatus, inputs []commonv1alpha1.InfraTaskInput, credentials map[string][]byte) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem, bool) {
+	startedAt := time.Now().Format(time.RFC3339)
+	if p.options.ReconcileMode == DriftCheckReconcileMode {
+		p.logger.Info("checking task drift", zap.String("name", currentTask.Name))
+		result, err := p.backend.Terraform.Plan(ctx, terraform.TerraformPlanInput{
+			Source:           currentTask.Terraform.Source,
+			Version:          currentTask.Terraform.Version,
+			TaskInputs:       inputs,
+			PreviousState:    lastTaskExecutionStatus.Task.State,
+			PreviousLockDeps: lastTaskExecutionStatus.Task.DependencyLock,
+			Credentials:      credentials,
+			Revision:         lastTaskExecutionStatus.Task.SourceRevision,
+		})
+		if err != nil {
+			p.logger.Info("failed to check task drift, applying task", zap.String("name", currentTask.Name), zap.Error(err))
+			return commonv1alpha1.TaskExecutionStatus{}, nil, false
+		}
+
+		if result.Add+result.Change+result.Destroy > 0 {
+			p.logger.Info("found drift in task", zap.String("name", currentTask.Name))
+			return commonv1alpha1.TaskExecutionStatus{}, nil, false
+		}
+	}
+
+	outputs, err := getLastKnownOutputs(lastTaskExecutionStatus)
+	if err != nil {
+		p.logger.Info("failed to read last known outputs, applying task", zap.String("name", currentTask.Name), zap.Error(err))
+		return commonv1alpha1.TaskExecutionStatus{}, nil, false
+	}
+
+	p.logger.Info("task unchanged since last execution", zap.String("name", currentTask.Name))
+	return commonv1alpha1.TaskExecutionStatus{
+		Name:        currentTask.Name,
+		Depends:     currentTask.Depends,
+		Inputs:      inputs,
+		Backend:     currentTask.Backend,
+		TaskOutputs: currentTask.TaskOutputs,
+		Task:        lastTaskExecutionStatus.Task,
+		Status:      TaskUnchangedStatus,
+		StartedAt:   startedAt,
+		FinishedAt:  time.Now().Format(time.RFC3339),
+	}, outputs, true
+}
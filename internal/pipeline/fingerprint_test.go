@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"encoding/base64"
+	"testing"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskFingerprint(t *testing.T) {
+	currentTask := commonv1alpha1.InfraTask{
+		Name:      "subnet",
+		Depends:   []string{"vpc"},
+		Terraform: commonv1alpha1.Terraform{Source: "oci://registry/subnet:latest", Version: "1.5.0"},
+	}
+	inputs := []commonv1alpha1.InfraTaskInput{{Key: "cidr", Value: "10.0.0.0/24"}, {Key: "az", Value: "us-east-1a"}}
+
+	fromOutput, err := getTaskFingerprint("sha256:abc", currentTask, inputs, ExecutionContext{
+		"vpc": {"tags": {Value: "{\n  \"env\": \"dev\"\n}", Type: "[\"map\",\"string\"]"}},
+	})
+	assert.NoError(t, err)
+
+	// same values read from a persisted state with other formatting and input order
+	fromState, err := getTaskFingerprint("sha256:abc", currentTask, []commonv1alpha1.InfraTaskInput{inputs[1], inputs[0]}, ExecutionContext{
+		"vpc": {"tags": {Value: "{\"env\":\"dev\"}", Type: "[\n  \"map\",\n  \"string\"\n]"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, fromOutput, fromState)
+
+	newDigest, err := getTaskFingerprint("sha256:def", currentTask, inputs, ExecutionContext{
+		"vpc": {"tags": {Value: "{\"env\":\"dev\"}", Type: "[\"map\",\"string\"]"}},
+	})
+	assert.NoError(t, err)
+	assert.NotEqual(t, fromOutput, newDigest)
+
+	newUpstreamOutput, err := getTaskFingerprint("sha256:abc", currentTask, inputs, ExecutionContext{
+		"vpc": {"tags": {Value: "{\"env\":\"prd\"}", Type: "[\"map\",\"string\"]"}},
+	})
+	assert.NoError(t, err)
+	assert.NotEqual(t, fromOutput, newUpstreamOutput)
+}
+
+func TestIsTaskUnchanged(t *testing.T) {
+	last := commonv1alpha1.TaskExecutionStatus{
+		Status: TaskAppliedStatus,
+		Task:   commonv1alpha1.TaskStatus{Fingerprint: "sha256:abc"},
+	}
+	assert.True(t, isTaskUnchanged(last, "sha256:abc"))
+	assert.False(t, isTaskUnchanged(last, "sha256:def"))
+
+	last.Status = TaskApplyErrorStatus
+	assert.False(t, isTaskUnchanged(last, "sha256:abc"))
+}
+
+func TestSensitiveOutputsAreReadFromState(t *testing.T) {
+	outputs := map[string]ExecutionOutputItem{
+		"endpoint": {Value: `"db.local"`, Type: `"string"`},
+		"password": {Value: `"secret"`, Type: `"string"`, Sensitive: true},
+	}
+
+	executionOutputs := getTaskExecutionOutputs(outputs)
+	assert.Equal(t, []commonv1alpha1.TaskExecutionOutput{
+		{Key: "endpoint", Value: `"db.local"`, Type: `"string"`},
+		{Key: "password", Value: "", Type: `"string"`, Sensitive: true},
+	}, executionOutputs)
+
+	state := base64.StdEncoding.EncodeToString([]byte(`{"outputs":{"endpoint":{"value":"db.local","type":"string"},"password":{"value":"secret","type":"string","sensitive":true}}}`))
+	lastKnownOutputs, err := getLastKnownOutputs(commonv1alpha1.TaskExecutionStatus{
+		Name:    "db",
+		Outputs: executionOutputs,
+		Task:    commonv1alpha1.TaskStatus{State: state},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, outputs, lastKnownOutputs)
+
+	_, err = getLastKnownOutputs(commonv1alpha1.TaskExecutionStatus{
+		Name:    "db",
+		Outputs: executionOutputs,
+		Task:    commonv1alpha1.TaskStatus{State: base64.StdEncoding.EncodeToString([]byte(`{"outputs":{}}`))},
+	})
+	assert.EqualError(t, err, "not found the sensitive output password in the state of task db")
+}
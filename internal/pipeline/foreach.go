@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/customerror"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var eachVariableRegexp = regexp.MustCompile(`{{\s*each\.(key|value)\s*}}`)
+
+// expandTasks replaces every task with forEach by one task per item, named
+// <task>-<key>. Dependencies on the parent task resolve to all its instances.
+// The items are kept by instance to resolve the each origin, the keys are
+// validated with the infra.
+func (p *pipelineCtx) expandTasks(infra commonv1alpha1.Infra) (commonv1alpha1.Infra, map[string][]string, error) {
+	instances := map[string][]string{}
+	p.forEachItems = map[string]commonv1alpha1.InfraTaskForEachItem{}
+	for _, t := range infra.Spec.Tasks {
+		if len(t.ForEach) == 0 {
+			continue
+		}
+
+		instances[t.Name] = []string{}
+		for _, item := range t.ForEach {
+			name := getForEachInstanceName(t.Name, getForEachItemKey(item))
+			if _, ok := p.forEachItems[name]; ok {
+				return infra, nil, getInvalidForEachError(fmt.Errorf("duplicated key %s in forEach of task %s", getForEachItemKey(item), t.Name))
+			}
+
+			p.forEachItems[name] = item
+			instances[t.Name] = append(instances[t.Name], name)
+		}
+	}
+
+	if len(instances) == 0 {
+		return infra, instances, nil
+	}
+
+	expandedTasks := []commonv1alpha1.InfraTask{}
+	for _, t := range infra.Spec.Tasks {
+		t.Depends = expandDependencies(t.Depends, instances)
+		if len(t.ForEach) == 0 {
+			expandedTasks = append(expandedTasks, t)
+			continue
+		}
+
+		for _, item := range t.ForEach {
+			expandedTasks = append(expandedTasks, getForEachInstance(t, item))
+		}
+	}
+
+	expandedInfra := *infra.DeepCopy()
+	expandedInfra.Spec.Tasks = expandedTasks
+	return expandedInfra, instances, nil
+}
+
+func getForEachInstance(parent commonv1alpha1.InfraTask, item commonv1alpha1.InfraTaskForEachItem) commonv1alpha1.InfraTask {
+	key := getForEachItemKey(item)
+	instance := *parent.DeepCopy()
+	instance.Name = getForEachInstanceName(parent.Name, key)
+	instance.Depends = parent.Depends
+	instance.ForEach = nil
+	// the names of the task outputs aren't expressions, inputs and condition
+	// resolve the each origin when they are interpolated
+	for i := range instance.TaskOutputs {
+		instance.TaskOutputs[i].Name = interpolateEachVariables(instance.TaskOutputs[i].Name, key, item.Value)
+	}
+
+	return instance
+}
+
+func interpolateEachVariables(value string, key string, itemValue string) string {
+	return eachVariableRegexp.ReplaceAllStringFunc(value, func(variable string) string {
+		if eachVariableRegexp.FindStringSubmatch(variable)[1] == "key" {
+			return key
+		}
+
+		return itemValue
+	})
+}
+
+func expandDependencies(depends []string, instances map[string][]string) []string {
+	if len(depends) == 0 {
+		return depends
+	}
+
+	expanded := []string{}
+	for _, dep := range depends {
+		if names, ok := instances[dep]; ok {
+			expanded = append(expanded, names...)
+			continue
+		}
+
+		expanded = append(expanded, dep)
+	}
+
+	return expanded
+}
+
+// getForEachItemKey returns the stable key of an item, items without key are
+// identified by their value.
+func getForEachItemKey(item commonv1alpha1.InfraTaskForEachItem) string {
+	if item.Key != "" {
+		return item.Key
+	}
+
+	return item.Value
+}
+
+func getForEachInstanceName(taskName string, key string) string {
+	return fmt.Sprintf("%s-%s", taskName, key)
+}
+
+// validateForEachKeys checks the keys of the expanded instances, they are part
+// of the task names used in the names of kubernetes resources.
+func (p *pipelineCtx) validateForEachKeys(infra commonv1alpha1.Infra) error {
+	for _, t := range infra.Spec.Tasks {
+		item, ok := p.forEachItems[t.Name]
+		if !ok {
+			continue
+		}
+
+		key := getForEachItemKey(item)
+		if errs := validation.IsDNS1123Label(key); len(errs) > 0 {
+			return getInvalidForEachError(fmt.Errorf("invalid key %q of task %s: %s", key, t.Name, strings.Join(errs, ", ")))
+		}
+	}
+
+	return nil
+}
+
+func getInvalidForEachError(err error) error {
+	return customerror.NewByErr(err, "INVALID_TASK_FOR_EACH", "Verify that the forEach items of the task have unique keys made of lowercase alphanumeric characters or -")
+}
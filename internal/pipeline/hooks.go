@@ -0,0 +1,210 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"go.uber.org/zap"
+)
+
+const (
+	PreApplyHookPhase    = "preApply"
+	PostApplyHookPhase   = "postApply"
+	PreDestroyHookPhase  = "preDestroy"
+	PostDestroyHookPhase = "postDestroy"
+)
+
+// maxHookOutputLength bounds the output of a hook kept in the status, only the
+// end of longer outputs is kept.
+const maxHookOutputLength = 4096
+
+var invalidHookEnvChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// hookEnvAllowList are the variables of the runner environment passed to the
+// hooks, the provider credentials given to the runner are never passed.
+var hookEnvAllowList = []string{"PATH", "HOME", "TMPDIR", "LANG", "LC_ALL", "TZ", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY"}
+
+// providerCredentialsEnv are the variables with the provider credentials set
+// by the controller in the runner environment.
+var providerCredentialsEnv = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"}
+
+// runHooks runs the hooks of a phase in order, it stops on the first failed
+// hook and returns the statuses of the executed hooks.
+func (p *pipelineCtx) runHooks(ctx context.Context, phase string, hooks []commonv1alpha1.InfraTaskHook, inputs []commonv1alpha1.InfraTaskInput, outputs map[string]ExecutionOutputItem) ([]commonv1alpha1.TaskHookStatus, error) {
+	statuses := []commonv1alpha1.TaskHookStatus{}
+	env := getHookEnv(inputs, outputs)
+	for _, hook := range hooks {
+		p.logger.Info("running hook", zap.String("phase", phase), zap.String("name", hook.Name))
+		status, err := runHook(ctx, hook, env)
+		status.Phase = phase
+		status.Output = maskSensitiveValues(status.Output, inputs, outputs)
+		if err != nil {
+			status.Error = commonv1alpha1.Error{
+				Message: fmt.Sprintf("%s hook %s failed: %s", phase, hook.Name, err.Error()),
+				Code:    "TASK_HOOK_ERROR",
+				Tip:     fmt.Sprintf("Verify the output of the %s hook %s", phase, hook.Name),
+			}
+			statuses = append(statuses, status)
+			return statuses, fmt.Errorf("%s hook %s failed: %w", phase, hook.Name, err)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func runHook(ctx context.Context, hook commonv1alpha1.InfraTaskHook, env []string) (commonv1alpha1.TaskHookStatus, error) {
+	status := commonv1alpha1.TaskHookStatus{
+		Name:      hook.Name,
+		StartedAt: time.Now().Format(time.RFC3339),
+		ExitCode:  -1,
+	}
+
+	ctx, cancel, err := getHookContext(ctx, hook)
+	if err != nil {
+		return status, err
+	}
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if hook.Script != "" {
+		cmd = exec.CommandContext(ctx, "sh", "-c", hook.Script)
+	} else {
+		cmd = exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	}
+	cmd.Env = env
+
+	output, err := cmd.CombinedOutput()
+	status.FinishedAt = time.Now().Format(time.RFC3339)
+	if cmd.ProcessState != nil {
+		status.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if len(output) > maxHookOutputLength {
+		output = output[len(output)-maxHookOutputLength:]
+	}
+	status.Output = string(output)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return status, fmt.Errorf("time limit exceeded")
+	}
+
+	return status, err
+}
+
+func getHookContext(ctx context.Context, hook commonv1alpha1.InfraTaskHook) (context.Context, context.CancelFunc, error) {
+	if hook.Timeout == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+
+	timeout, err := time.ParseDuration(hook.Timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timeout %s for hook %s: %w", hook.Timeout, hook.Name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// getHookEnv adds the inputs and outputs of the task to the allowed variables
+// of the runner environment, string outputs are unquoted and the others are
+// kept as JSON.
+func getHookEnv(inputs []commonv1alpha1.InfraTaskInput, outputs map[string]ExecutionOutputItem) []string {
+	env := []string{}
+	for _, key := range hookEnvAllowList {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+
+	for _, i := range inputs {
+		env = append(env, fmt.Sprintf("%s=%s", getHookEnvName("INPUT_", i.Key), i.Value))
+	}
+
+	for key, o := range outputs {
+		value := o.Value
+		if s, ok := decodeOutputValue(o.Value).(string); ok {
+			value = s
+		}
+
+		env = append(env, fmt.Sprintf("%s=%s", getHookEnvName("OUTPUT_", key), value))
+	}
+
+	return env
+}
+
+func getHookEnvName(prefix string, key string) string {
+	return prefix + invalidHookEnvChars.ReplaceAllString(strings.ToUpper(key), "_")
+}
+
+// maskSensitiveValues hides the sensitive inputs and outputs printed by a hook,
+// and the provider credentials hooks could still read from the runner process.
+func maskSensitiveValues(output string, inputs []commonv1alpha1.InfraTaskInput, outputs map[string]ExecutionOutputItem) string {
+	values := []string{}
+	for _, key := range providerCredentialsEnv {
+		values = append(values, os.Getenv(key))
+	}
+
+	for _, i := range inputs {
+		if i.Sensitive {
+			values = append(values, i.Value)
+		}
+	}
+
+	for _, o := range outputs {
+		if o.Sensitive {
+			values = append(values, o.Value)
+			if s, ok := decodeOutputValue(o.Value).(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	for _, v := range values {
+		if v != "" {
+			output = strings.ReplaceAll(output, v, "***")
+		}
+	}
+
+	return output
+}
+
+func validateTaskHooks(t commonv1alpha1.InfraTask) error {
+	phases := []string{PreApplyHookPhase, PostApplyHookPhase, PreDestroyHookPhase, PostDestroyHookPhase}
+	for i, hooks := range [][]commonv1alpha1.InfraTaskHook{t.Hooks.PreApply, t.Hooks.PostApply, t.Hooks.PreDestroy, t.Hooks.PostDestroy} {
+		phase := phases[i]
+		for _, hook := range hooks {
+			if hook.Name == "" {
+				return fmt.Errorf("found a %s hook without name in task %s", phase, t.Name)
+			}
+
+			if (len(hook.Command) == 0) == (hook.Script == "") {
+				return fmt.Errorf("%s hook %s of task %s must have either a command or a script", phase, hook.Name, t.Name)
+			}
+
+			_, cancel, err := getHookContext(context.Background(), hook)
+			if err != nil {
+				return err
+			}
+			cancel()
+		}
+	}
+
+	return nil
+}
+
+func getTaskHookError(err error) commonv1alpha1.Error {
+	return commonv1alpha1.Error{
+		Message: err.Error(),
+		Code:    "TASK_HOOK_ERROR",
+		Tip:     "Verify the output of the failed hook in the task status",
+	}
+}
@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRunHooks(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	p := &pipelineCtx{logger: logger}
+	inputs := []commonv1alpha1.InfraTaskInput{
+		{Key: "queue-name", Value: "orders"},
+		{Key: "token", Value: "s3cr3t", Sensitive: true},
+	}
+	outputs := map[string]ExecutionOutputItem{
+		"endpoint": {Value: `"https://orders.local"`, Type: `"string"`},
+		"ports":    {Value: `[80,443]`, Type: `["list","number"]`},
+	}
+
+	statuses, err := p.runHooks(context.Background(), PostApplyHookPhase, []commonv1alpha1.InfraTaskHook{
+		{Name: "env", Script: `echo "$INPUT_QUEUE_NAME $OUTPUT_ENDPOINT $OUTPUT_PORTS $INPUT_TOKEN"`},
+		{Name: "true", Command: []string{"true"}},
+	}, inputs, outputs)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, PostApplyHookPhase, statuses[0].Phase)
+	assert.Equal(t, 0, statuses[0].ExitCode)
+	assert.Equal(t, "orders https://orders.local [80,443] ***\n", statuses[0].Output)
+
+	statuses, err = p.runHooks(context.Background(), PreDestroyHookPhase, []commonv1alpha1.InfraTaskHook{
+		{Name: "drain", Script: "echo draining; exit 3"},
+		{Name: "never", Command: []string{"true"}},
+	}, inputs, nil)
+	assert.EqualError(t, err, "preDestroy hook drain failed: exit status 3")
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, 3, statuses[0].ExitCode)
+	assert.Equal(t, "draining\n", statuses[0].Output)
+	assert.Equal(t, "TASK_HOOK_ERROR", statuses[0].Error.Code)
+
+	_, err = p.runHooks(context.Background(), PostApplyHookPhase, []commonv1alpha1.InfraTaskHook{
+		{Name: "slow", Script: "exec sleep 5", Timeout: "10ms"},
+	}, nil, nil)
+	assert.EqualError(t, err, "postApply hook slow failed: time limit exceeded")
+}
+
+func TestRunHooksHidesProviderCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "provider-secret")
+	logger, _ := zap.NewDevelopment()
+	p := &pipelineCtx{logger: logger}
+
+	statuses, err := p.runHooks(context.Background(), PostApplyHookPhase, []commonv1alpha1.InfraTaskHook{
+		{Name: "env", Script: `echo "key=$AWS_ACCESS_KEY_ID secret=$AWS_SECRET_ACCESS_KEY"; env`},
+		{Name: "leak", Command: []string{"echo", "AKIAEXAMPLE", "provider-secret"}},
+	}, nil, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, statuses[0].Output, "key= secret=\n")
+	assert.NotContains(t, statuses[0].Output, "AWS_")
+	assert.Equal(t, "*** ***\n", statuses[1].Output)
+}
+
+func TestValidateTaskHooks(t *testing.T) {
+	assert.NoError(t, validateTaskHooks(commonv1alpha1.InfraTask{Name: "queue", Hooks: commonv1alpha1.InfraTaskHooks{
+		PreDestroy: []commonv1alpha1.InfraTaskHook{{Name: "drain", Command: []string{"./drain.sh"}, Timeout: "5m"}},
+	}}))
+
+	err := validateTaskHooks(commonv1alpha1.InfraTask{Name: "queue", Hooks: commonv1alpha1.InfraTaskHooks{
+		PostApply: []commonv1alpha1.InfraTaskHook{{Name: "smoke", Command: []string{"curl"}, Script: "curl"}},
+	}})
+	assert.EqualError(t, err, "postApply hook smoke of task queue must have either a command or a script")
+
+	err = validateTaskHooks(commonv1alpha1.InfraTask{Name: "queue", Hooks: commonv1alpha1.InfraTaskHooks{
+		PreApply: []commonv1alpha1.InfraTaskHook{{Script: "true"}},
+	}})
+	assert.EqualError(t, err, "found a preApply hook without name in task queue")
+}
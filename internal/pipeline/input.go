@@ -1,64 +1,159 @@
 package pipeline
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
-	"github.com/octopipe/cloudx/internal/lex"
+	"github.com/octopipe/cloudx/internal/expression"
 	"github.com/octopipe/cloudx/internal/task"
 	"github.com/octopipe/cloudx/internal/taskoutput"
+	"github.com/octopipe/cloudx/internal/variable"
 	"k8s.io/apimachinery/pkg/types"
 )
 
 func (p *pipelineCtx) interpolateTaskInputsByExecutionContext(task commonv1alpha1.InfraTask, executionContext ExecutionContext) ([]commonv1alpha1.InfraTaskInput, error) {
 	inputs := []commonv1alpha1.InfraTaskInput{}
 	for _, i := range task.Inputs {
-		tokens := lex.Tokenize(i.Value)
-		data := map[string]string{}
-		sensitive := false
-		for _, t := range tokens {
-			if t.Type == lex.TokenVariable {
-				s := strings.Split(strings.Trim(t.Value, " "), ".")
-				if len(s) != 3 {
-					return nil, fmt.Errorf("malformed input variable %s with value %s", i.Key, i.Value)
-				}
-
-				value, isSensitive, err := p.getDataByOrigin(s[0], s[1], s[2], executionContext)
-				if err != nil {
-					return nil, err
-				}
-
-				if isSensitive {
-					sensitive = isSensitive
-				}
-
-				data[t.Value] = strings.Trim(value, "\"")
-			}
+		value, sensitive, err := p.interpolateValue(task.Name, i.Key, i.Value, executionContext)
+		if err != nil {
+			return nil, err
 		}
 
 		inputs = append(inputs, commonv1alpha1.InfraTaskInput{
 			Key:       i.Key,
-			Value:     lex.Interpolate(tokens, data),
+			Value:     value,
 			Sensitive: sensitive,
+			Type:      i.Type,
 		})
 	}
 
 	return inputs, nil
 }
 
+// interpolateValue evaluates the expressions of a value of a task, it returns
+// whether any of the resolved values is sensitive.
+func (p *pipelineCtx) interpolateValue(taskName string, key string, rawValue string, executionContext ExecutionContext) (string, bool, error) {
+	template, err := expression.ParseTemplate(rawValue)
+	if err != nil {
+		return "", false, fmt.Errorf("input %s: %w", key, err)
+	}
+
+	value, sensitive, err := template.Evaluate(pipelineResolver{p: p, taskName: taskName, executionContext: executionContext})
+	if err != nil {
+		return "", false, fmt.Errorf("input %s: %w", key, err)
+	}
+
+	return value, sensitive, nil
+}
+
+// pipelineResolver resolves the paths of the expressions, the first two
+// segments are the name and the attribute of the origin, variables only have
+// a name. Values of secrets are always sensitive. The each origin resolves the
+// forEach item of the task, its value is never parsed as an expression.
+type pipelineResolver struct {
+	p                *pipelineCtx
+	taskName         string
+	executionContext ExecutionContext
+}
+
+func (r pipelineResolver) Resolve(origin string, path []string) (expression.Value, int, error) {
+	if origin == task.EachInterpolationOrigin && len(path) > 0 {
+		item, ok := r.p.forEachItems[r.taskName]
+		if !ok {
+			return expression.Value{}, 0, fmt.Errorf("origin each is only valid in tasks with forEach")
+		}
+
+		switch path[0] {
+		case "key":
+			return expression.Value{Data: getForEachItemKey(item)}, 1, nil
+		case "value":
+			return expression.Value{Data: item.Value}, 1, nil
+		default:
+			return expression.Value{}, 0, fmt.Errorf("malformed reference, expected each.key or each.value")
+		}
+	}
+
+	if origin == task.VarInterpolationOrigin && len(path) > 0 {
+		variable, ok := r.p.variables[path[0]]
+		if !ok {
+			return expression.Value{}, 0, expression.NotFoundError{Message: fmt.Sprintf("not found variable %s", path[0])}
+		}
+
+		return expression.Value{Data: variable.Value, Sensitive: variable.Sensitive}, 1, nil
+	}
+
+	if len(path) < 2 {
+		return expression.Value{}, 0, fmt.Errorf("malformed reference, expected %s.<name>.<attr>", origin)
+	}
+
+	switch origin {
+	case task.SecretInterpolationOrigin, task.ConfigMapInterpolationOrigin:
+		value, err := r.p.getClusterValue(origin, path[0], path[1])
+		if err != nil {
+			return expression.Value{}, 0, err
+		}
+
+		return expression.Value{Data: value, Sensitive: origin == task.SecretInterpolationOrigin}, 2, nil
+	default:
+		value, sensitive, err := r.p.getDataByOrigin(origin, path[0], path[1], r.executionContext)
+		if err != nil {
+			return expression.Value{}, 0, err
+		}
+
+		return expression.Value{Data: decodeOutputValue(value), Sensitive: sensitive}, 2, nil
+	}
+}
+
+// getClusterValue reads a key of a secret or config map of the infra namespace
+// through the controller, the runner has no access to them.
+func (p *pipelineCtx) getClusterValue(origin string, name string, key string) (string, error) {
+	method := "VariableRPCHandler.GetConfigMapKey"
+	if origin == task.SecretInterpolationOrigin {
+		method = "VariableRPCHandler.GetSecretKey"
+	}
+
+	reply := variable.RPCGetKeyReply{}
+	err := p.rpcClient.Call(method, variable.RPCGetKeyArgs{InfraRef: p.infraRef, Name: name, Key: key}, &reply)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key %s of %s %s: %w", key, origin, name, err)
+	}
+
+	return reply.Value, nil
+}
+
+// decodeOutputValue decodes the JSON encoded terraform outputs, so lists and
+// maps can be indexed. Values that aren't JSON are used as plain strings.
+func decodeOutputValue(value string) interface{} {
+	var data interface{}
+	err := json.Unmarshal([]byte(value), &data)
+	if err != nil {
+		return value
+	}
+
+	return data
+}
+
 func (p *pipelineCtx) getDataByOrigin(origin string, name string, attr string, executionContext ExecutionContext) (string, bool, error) {
 	switch origin {
 	case task.ThisInterpolationOrigin:
 		p.logger.Info("interpolate this origin")
 		execution, ok := executionContext[name]
 		if !ok {
-			return "", false, fmt.Errorf("not found task %s in execution context", name)
+			return "", false, expression.NotFoundError{Message: fmt.Sprintf("not found task %s in execution context", name)}
 		}
 
 		executionAttr, ok := execution[attr]
 		if !ok {
-			return "", false, fmt.Errorf("not found attr %s in finished task execution %s", attr, name)
+			executionAttr, ok = execution[unknownOutputsKey]
+		}
+		if !ok {
+			return "", false, expression.NotFoundError{Message: fmt.Sprintf("not found attr %s in finished task execution %s", attr, name)}
+		}
+
+		if executionAttr.Unknown {
+			return "", false, expression.UnknownError{Message: fmt.Sprintf("attr %s of task %s is known after apply", attr, name)}
 		}
 
 		return executionAttr.Value, executionAttr.Sensitive, nil
@@ -67,20 +162,32 @@ func (p *pipelineCtx) getDataByOrigin(origin string, name string, attr string, e
 		p.logger.Info("interpolate this task-output")
 		taskOutput := commonv1alpha1.TaskOutput{}
 		err := p.rpcClient.Call("TaskOutputRPCHandler.GetTaskOutput", taskoutput.RPCGetTaskOutputArgs{
-			Ref: types.NamespacedName{Name: name, Namespace: "default"},
+			Ref:      getTaskOutputRef(name, p.namespace),
+			InfraRef: p.infraRef,
 		}, &taskOutput)
 		if err != nil {
 			return "", false, err
 		}
 
 		for _, out := range taskOutput.Spec.Outputs {
-			if out.Key == attr {
-				return out.Value, out.Sensitive, nil
+			if out.Key == attr && out.Value != nil {
+				return string(out.Value.Raw), out.Sensitive, nil
 			}
 		}
 
-		return "", false, fmt.Errorf("not found attr in connection-interface %s", name)
+		return "", false, expression.NotFoundError{Message: fmt.Sprintf("not found attr %s in task output %s", attr, name)}
 	default:
 		return "", false, fmt.Errorf("invalid origin type %s", origin)
 	}
 }
+
+// getTaskOutputRef parses task output names written as <namespace>/<name>,
+// names without namespace are in the namespace of the infra.
+func getTaskOutputRef(name string, namespace string) types.NamespacedName {
+	s := strings.SplitN(name, "/", 2)
+	if len(s) == 2 {
+		return types.NamespacedName{Namespace: s[0], Name: s[1]}
+	}
+
+	return types.NamespacedName{Name: name, Namespace: namespace}
+}
@@ -1,7 +1,9 @@
 package pipeline
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,7 +14,13 @@ import (
 	"github.com/octopipe/cloudx/internal/rpcclient"
 	"github.com/octopipe/cloudx/internal/taskoutput"
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	ApplyAction   = "APPLY"
+	DestroyAction = "DESTROY"
+	PlanAction    = "PLAN"
 )
 
 const (
@@ -20,151 +28,506 @@ const (
 	InfraErrorStatus   = "ERROR"
 	InfraRunningStatus = "RUNNING"
 	InfraTimeoutStatus = "TIMEOUT"
+	// InfraWaitingApprovalStatus is published while any task waits for approval
+	InfraWaitingApprovalStatus = "WAITING_APPROVAL"
+)
+
+const (
+	TaskAppliedStatus          = "APPLIED"
+	TaskApplyErrorStatus       = "APPLY_ERROR"
+	TaskDestroyed              = "DESTROYED"
+	TaskDestroyErrorStatus     = "DESTROY_ERROR"
+	TaskPlannedStatus          = "PLANNED"
+	TaskPlanErrorStatus        = "PLAN_ERROR"
+	TaskTimeoutStatus          = "TIMEOUT"
+	TaskSkippedStatus          = "SKIPPED"
+	TaskUnchangedStatus        = "UNCHANGED"
+	TaskConditionSkippedStatus = "CONDITION_SKIPPED"
+	TaskWaitingApprovalStatus  = "WAITING_APPROVAL"
+	TaskRetainedStatus         = "RETAINED"
 )
 
 const (
-	TaskAppliedStatus      = "APPLIED"
-	TaskApplyErrorStatus   = "APPLY_ERROR"
-	TaskDestroyed          = "DESTROYED"
-	TaskDestroyErrorStatus = "DESTROY_ERROR"
+	FailFastFailurePolicy            = "FailFast"
+	ContinueIndependentFailurePolicy = "ContinueIndependent"
 )
 
+const DefaultMaxParallelTasks = 10
+
+const (
+	ForceReconcileMode      = "force"
+	DriftCheckReconcileMode = "drift-check"
+	ResumeReconcileMode     = "resume"
+)
+
+// ExecutionOptions customizes a single execution of the pipeline.
+type ExecutionOptions struct {
+	// ReconcileMode forces the apply of unchanged tasks (force), verifies
+	// them against the real infrastructure before skipping (drift-check) or
+	// restarts the last execution from its failed tasks (resume)
+	ReconcileMode string
+	// Targets restricts the execution to these tasks, with their dependencies
+	// on apply and their dependents on destroy
+	Targets []string
+}
+
+// ExecutionOutputItem is a terraform output, Value and Type are kept as the
+// raw JSON returned by terraform so list and map outputs can be indexed.
+// Unknown outputs of a plan have no value, they are omitted from the JSON so
+// the fingerprints of the applied outputs don't change.
 type ExecutionOutputItem struct {
 	Value     string
 	Sensitive bool
 	Type      string
+	Unknown   bool `json:",omitempty"`
 }
 
 type ExecutionContext map[string]map[string]ExecutionOutputItem
 
-type ActionFuncType func(taskName string, exectionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem)
+type ActionFuncType func(ctx context.Context, taskName string, exectionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem)
 
 type pipelineCtx struct {
-	logger    *zap.Logger
-	backend   backend.Backend
-	rpcClient rpcclient.Client
+	logger                  *zap.Logger
+	backend                 backend.Backend
+	rpcClient               rpcclient.Client
+	defaultMaxParallelTasks int
 
 	mu               sync.Mutex
 	executionContext ExecutionContext
+	failurePolicy    string
+	maxParallelTasks int
+	options          ExecutionOptions
+	lastExecution    map[string]commonv1alpha1.TaskExecutionStatus
+	variables        map[string]ExecutionOutputItem
+	namespace        string
+	infraRef         types.NamespacedName
+	// forEachItems are the items of the instances of forEach tasks
+	forEachItems map[string]commonv1alpha1.InfraTaskForEachItem
+	// waitingApprovalChan receives the tasks of the running graph that are
+	// waiting for approval
+	waitingApprovalChan chan commonv1alpha1.TaskExecutionStatus
 }
 
 type Pipeline interface {
-	Start(action string, infra commonv1alpha1.Infra, statusChan chan commonv1alpha1.ExecutionStatus)
+	Start(ctx context.Context, action string, infra commonv1alpha1.Infra, options ExecutionOptions, statusChan chan commonv1alpha1.ExecutionStatus)
 }
 
-func NewPipeline(logger *zap.Logger, rpcClient rpcclient.Client, backend backend.Backend) Pipeline {
+// NewPipeline creates a pipeline, defaultMaxParallelTasks bounds the tasks
+// executed at once for infras without maxParallelTasks.
+func NewPipeline(logger *zap.Logger, rpcClient rpcclient.Client, backend backend.Backend, defaultMaxParallelTasks int) Pipeline {
 	return &pipelineCtx{
-		logger:           logger,
-		backend:          backend,
-		rpcClient:        rpcClient,
-		executionContext: make(ExecutionContext),
+		logger:                  logger,
+		backend:                 backend,
+		rpcClient:               rpcClient,
+		executionContext:        make(ExecutionContext),
+		defaultMaxParallelTasks: defaultMaxParallelTasks,
 	}
 }
 
-func (p *pipelineCtx) Start(action string, infra commonv1alpha1.Infra, statusChan chan commonv1alpha1.ExecutionStatus) {
-	if action == "APPLY" {
+func (p *pipelineCtx) Start(ctx context.Context, action string, infra commonv1alpha1.Infra, options ExecutionOptions, statusChan chan commonv1alpha1.ExecutionStatus) {
+	if options.ReconcileMode != "" && options.ReconcileMode != ForceReconcileMode && options.ReconcileMode != DriftCheckReconcileMode && options.ReconcileMode != ResumeReconcileMode {
+		sendStatus(statusChan, getInvalidInfraStatus(action, infra, customerror.New(
+			fmt.Sprintf("invalid reconcile mode %s", options.ReconcileMode),
+			"INVALID_RECONCILE_MODE",
+			fmt.Sprintf("Use %s, %s or %s as reconcile mode", ForceReconcileMode, DriftCheckReconcileMode, ResumeReconcileMode),
+		)))
+		return
+	}
+
+	// destroys must run even when the current spec is invalid, they only
+	// depend on the last execution
+	expandedInfra, instances, err := p.expandTasks(infra)
+	if err != nil && action != DestroyAction {
+		sendStatus(statusChan, getInvalidInfraStatus(action, infra, customerror.Unwrap(err)))
+		return
+	}
+
+	if err == nil {
+		infra = expandedInfra
+		options.Targets = expandDependencies(options.Targets, instances)
+	}
+
+	if action != DestroyAction {
+		err := p.validateInfra(infra)
+		if err != nil {
+			p.logger.Error("invalid infra", zap.Error(err))
+			sendStatus(statusChan, getInvalidInfraStatus(action, infra, customerror.Unwrap(err)))
+			return
+		}
+	}
+
+	variables, err := p.resolveVariables(infra)
+	if err != nil && action != DestroyAction {
+		p.logger.Error("failed to resolve variables", zap.Error(err))
+		sendStatus(statusChan, getInvalidInfraStatus(action, infra, customerror.Unwrap(err)))
+		return
+	}
+
+	executionTimeout, err := getExecutionTimeout(infra)
+	if err != nil {
+		sendStatus(statusChan, getInvalidInfraStatus(action, infra, customerror.NewByErr(
+			err,
+			"INVALID_EXECUTION_TIMEOUT",
+			"Verify that the execution timeout is a valid duration, e.g. 10m",
+		)))
+		return
+	}
+
+	p.options = options
+	p.variables = variables
+	p.namespace = infra.Namespace
+	p.infraRef = types.NamespacedName{Name: infra.Name, Namespace: infra.Namespace}
+	p.failurePolicy = infra.Spec.FailurePolicy
+	p.maxParallelTasks = infra.Spec.MaxParallelTasks
+	if p.maxParallelTasks <= 0 {
+		p.maxParallelTasks = p.defaultMaxParallelTasks
+	}
+	p.lastExecution = map[string]commonv1alpha1.TaskExecutionStatus{}
+	for _, t := range infra.Status.LastExecution.Tasks {
+		p.lastExecution[t.Name] = t
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, executionTimeout)
+	defer cancel()
+
+	switch {
+	case action == ApplyAction && len(options.Targets) > 0:
+		applyGraph, err := p.getTargetedApplyGraph(infra, options.Targets)
+		if err != nil {
+			sendStatus(statusChan, getInvalidInfraStatus(action, infra, customerror.Unwrap(err)))
+			return
+		}
+
+		retainedTasks := p.getRetainedTasks(infra, applyGraph)
+		p.seedExecutionContext(retainedTasks)
+		p.logger.Info("apply targeted tasks...", zap.Strings("targets", options.Targets))
+		p.Run(ctx, applyGraph, p.apply(infra), retainedTasks, statusChan)
+	case action == DestroyAction && len(options.Targets) > 0:
+		tasksForDestroy, err := p.getTasksForTargetedDestroy(infra, options.Targets)
+		if err != nil {
+			sendStatus(statusChan, getInvalidInfraStatus(action, infra, customerror.Unwrap(err)))
+			return
+		}
+
+		destroyGraph := p.getDestroyGraph(infra, tasksForDestroy)
+		p.logger.Info("destroying targeted tasks...", zap.Strings("targets", options.Targets))
+		p.Run(ctx, destroyGraph, p.destroy(infra), p.getRetainedTasks(infra, destroyGraph), statusChan)
+	case action == ApplyAction:
 		tasksForDestroy := p.diffTasksForApply(infra)
-		destroyGraph := p.getDestroyGraph(tasksForDestroy)
+		destroyGraph := p.getDestroyGraph(infra, tasksForDestroy)
 		applyGraph := p.getApplyGraph(infra)
 		p.logger.Info("destroying diff tasks...")
-		p.Run(destroyGraph, p.destroy(infra), nil)
+		destroyStatus := p.Run(ctx, destroyGraph, p.destroy(infra), nil, nil)
+
+		// removed tasks that failed to be destroyed keep their state to be
+		// destroyed by the next executions, retained tasks are kept until the
+		// next execution to show where their state was archived
+		retainedTasks := []commonv1alpha1.TaskExecutionStatus{}
+		for _, t := range destroyStatus.Tasks {
+			if t.Status != TaskDestroyed {
+				retainedTasks = append(retainedTasks, t)
+			}
+		}
+
+		if options.ReconcileMode == ResumeReconcileMode {
+			resumedTasks := p.getResumedTasks(infra)
+			for _, t := range resumedTasks {
+				delete(applyGraph, t.Name)
+			}
+
+			p.seedExecutionContext(resumedTasks)
+			retainedTasks = append(retainedTasks, resumedTasks...)
+			p.logger.Info("resuming last execution", zap.Int("resumed", len(resumedTasks)))
+		}
+
 		p.logger.Info("apply diff tasks...")
-		p.Run(applyGraph, p.apply(infra), statusChan)
-	} else {
-		tasksForDestroy := p.diffTasksForApply(commonv1alpha1.Infra{})
-		destroyGraph := p.getDestroyGraph(tasksForDestroy)
+		p.Run(ctx, applyGraph, p.apply(infra), retainedTasks, statusChan)
+	case action == PlanAction:
+		tasksForDestroy := p.diffTasksForApply(infra)
+		planGraph := p.getApplyGraph(infra)
+		for task, deps := range p.getDestroyGraph(infra, tasksForDestroy) {
+			planGraph[task] = deps
+		}
+		p.logger.Info("planning tasks...")
+		p.Run(ctx, planGraph, p.plan(infra, tasksForDestroy), nil, statusChan)
+	default:
+		// every task of the last execution is destroyed with the infra
+		tasksForDestroy := p.diffTasksForApply(commonv1alpha1.Infra{Status: infra.Status})
+		destroyGraph := p.getDestroyGraph(infra, tasksForDestroy)
 		p.logger.Info("destroying all tasks...")
-		p.Run(destroyGraph, p.destroy(infra), statusChan)
+		p.Run(ctx, destroyGraph, p.destroy(infra), nil, statusChan)
 	}
 
 }
 
-func (e *pipelineCtx) Run(graph map[string][]string, action ActionFuncType, statusChan chan commonv1alpha1.ExecutionStatus) {
-	eg := new(errgroup.Group)
+type taskResult struct {
+	node    string
+	status  commonv1alpha1.TaskExecutionStatus
+	outputs map[string]ExecutionOutputItem
+}
+
+// Run executes the graph in dependency order, a node is started as soon as all
+// its dependencies finish and at most maxParallelTasks nodes run at once. The
+// retained tasks are not executed, but are kept in the published status.
+func (e *pipelineCtx) Run(ctx context.Context, graph map[string][]string, action ActionFuncType, retainedTasks []commonv1alpha1.TaskExecutionStatus, statusChan chan commonv1alpha1.ExecutionStatus) commonv1alpha1.ExecutionStatus {
+	status := commonv1alpha1.ExecutionStatus{
+		Tasks: append([]commonv1alpha1.TaskExecutionStatus{}, retainedTasks...),
+	}
 	inDegrees := make(map[string]int)
-	status := commonv1alpha1.ExecutionStatus{}
+	dependents := make(map[string][]string)
+	scheduled := map[string]bool{}
+	results := make(chan taskResult)
+	waitingTasks := map[string]commonv1alpha1.TaskExecutionStatus{}
+	ready := []string{}
+	running := 0
+	finished := 0
+	var failure error
 
 	if len(graph) == 0 {
 		e.logger.Info("nothing to execute")
-		return
+		status.Status = InfraSuccessStatus
+		sendStatus(statusChan, status)
+		return status
 	}
 
+	// dependencies outside of the graph are already satisfied
 	for node, deps := range graph {
-		inDegrees[node] = len(deps)
+		for _, dep := range deps {
+			if _, ok := graph[dep]; ok {
+				inDegrees[node]++
+				dependents[dep] = append(dependents[dep], node)
+			}
+		}
+	}
+
+	for node := range graph {
+		if inDegrees[node] == 0 {
+			ready = append(ready, node)
+		}
 	}
+	sort.Strings(ready)
+
+	launch := func() {
+		for len(ready) > 0 && ctx.Err() == nil && (e.maxParallelTasks <= 0 || running < e.maxParallelTasks) {
+			node := ready[0]
+			ready = ready[1:]
+			if scheduled[node] {
+				continue
+			}
+
+			scheduled[node] = true
+			running++
+			executionContext := e.getExecutionContextSnapshot()
+			go func() {
+				taskStatus, taskOutput := action(ctx, node, executionContext)
+				results <- taskResult{node: node, status: taskStatus, outputs: taskOutput}
+			}()
+		}
+	}
+
+	e.waitingApprovalChan = make(chan commonv1alpha1.TaskExecutionStatus)
+	launch()
+	for running > 0 {
+		var result taskResult
+		select {
+		case waitingStatus := <-e.waitingApprovalChan:
+			waitingTasks[waitingStatus.Name] = waitingStatus
+			sendStatus(statusChan, getWaitingApprovalStatus(status, waitingTasks))
+			continue
+		case result = <-results:
+		}
+
+		delete(waitingTasks, result.node)
+		running--
+		finished++
+		if !isTaskFailed(result.status) {
+			result.status.Outputs = getTaskExecutionOutputs(result.outputs)
+		}
+		status.Tasks = append(status.Tasks, result.status)
+		e.mu.Lock()
+		e.executionContext[result.node] = result.outputs
+		e.mu.Unlock()
+
+		if isTaskFailed(result.status) {
+			err := customerror.New(result.status.Error.Message, result.status.Error.Code, result.status.Error.Tip)
+			if failure == nil {
+				failure = err
+			}
 
-	go func() {
-		time.Sleep(10 * time.Minute)
+			if e.failurePolicy == ContinueIndependentFailurePolicy {
+				for _, dependent := range getTransitiveDependents(graph, result.node) {
+					if scheduled[dependent] {
+						continue
+					}
+
+					scheduled[dependent] = true
+					finished++
+					status.Tasks = append(status.Tasks, e.getSkippedTaskStatus(dependent, graph[dependent], commonv1alpha1.Error{
+						Message: fmt.Sprintf("skipped because the task %s failed", result.node),
+						Code:    "TASK_DEPENDENCY_FAILED",
+						Tip:     fmt.Sprintf("Fix the task %s to execute this task", result.node),
+					}))
+				}
+			}
+		} else {
+			newReady := []string{}
+			for _, dependent := range dependents[result.node] {
+				inDegrees[dependent]--
+				if inDegrees[dependent] == 0 {
+					newReady = append(newReady, dependent)
+				}
+			}
+			sort.Strings(newReady)
+			ready = append(ready, newReady...)
+		}
+
+		if len(waitingTasks) > 0 {
+			sendStatus(statusChan, getWaitingApprovalStatus(status, waitingTasks))
+		} else {
+			sendStatus(statusChan, status)
+		}
+
+		if failure == nil || e.failurePolicy == ContinueIndependentFailurePolicy {
+			launch()
+		}
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
 		e.logger.Info("time limit exceeded")
-		statusChan <- commonv1alpha1.ExecutionStatus{
-			Status: InfraTimeoutStatus,
-			Error: commonv1alpha1.Error{
-				Message: "time limit exceeded",
-				Code:    "TIME_LIMIT_EXCEEDED",
-				Tip:     "Verify if your infrastructure is not stuck in some task.",
-			},
+		e.skipUnscheduledTasks(graph, scheduled, &status, commonv1alpha1.Error{
+			Message: "skipped because the time limit was exceeded",
+			Code:    "TIME_LIMIT_EXCEEDED",
+		})
+		status.Error = commonv1alpha1.Error{
+			Message: "time limit exceeded",
+			Code:    "TIME_LIMIT_EXCEEDED",
+			Tip:     "Verify if your infrastructure is not stuck in some task.",
 		}
-	}()
+		status.Status = InfraTimeoutStatus
+		sendStatus(statusChan, status)
+		return status
+	}
 
-	for {
-		for node, deps := range inDegrees {
-			if _, ok := e.executionContext[node]; !ok && deps == 0 {
-				eg.Go(func(node string) func() error {
-					return func() error {
+	if failure == nil && ctx.Err() != nil {
+		failure = customerror.NewByErr(ctx.Err(), "EXECUTION_CANCELED", "Verify if the runner was not interrupted")
+	}
 
-						taskStatus, taskOutput := action(node, e.executionContext)
+	if failure != nil {
+		e.logger.Error("find errors in execution...", zap.Error(failure))
+		cErr := customerror.Unwrap(failure)
+		e.skipUnscheduledTasks(graph, scheduled, &status, commonv1alpha1.Error{
+			Message: "skipped because the execution failed",
+			Code:    "EXECUTION_FAILED",
+		})
+		status.Error = commonv1alpha1.Error{
+			Message: cErr.Message,
+			Code:    cErr.Code,
+			Tip:     cErr.Tip,
+		}
+		status.Status = InfraErrorStatus
+		sendStatus(statusChan, status)
+		return status
+	}
 
-						e.mu.Lock()
-						defer e.mu.Unlock()
+	e.logger.Info("executed all tasks successfully", zap.Int("tasks", finished))
+	status.Status = InfraSuccessStatus
+	sendStatus(statusChan, status)
+	return status
+}
 
-						status.Tasks = append(status.Tasks, taskStatus)
+// getExecutionContextSnapshot copies the outputs known so far, so running
+// tasks never read the execution context while it is updated.
+func (e *pipelineCtx) getExecutionContextSnapshot() ExecutionContext {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
-						statusChan <- status
-						e.executionContext[node] = taskOutput
+	snapshot := make(ExecutionContext, len(e.executionContext))
+	for node, outputs := range e.executionContext {
+		snapshot[node] = outputs
+	}
 
-						if taskStatus.Status == TaskApplyErrorStatus || taskStatus.Status == TaskDestroyErrorStatus {
-							return customerror.New(taskStatus.Error.Message, taskStatus.Error.Code, taskStatus.Error.Tip)
-						}
+	return snapshot
+}
 
-						for n, deps := range graph {
-							for _, dep := range deps {
-								if dep == node {
-									inDegrees[n]--
-								}
-							}
-						}
+// skipUnscheduledTasks marks the tasks abandoned by the execution as skipped,
+// so their last known state is kept for the next executions.
+func (e *pipelineCtx) skipUnscheduledTasks(graph map[string][]string, scheduled map[string]bool, status *commonv1alpha1.ExecutionStatus, reason commonv1alpha1.Error) {
+	for node, deps := range graph {
+		if !scheduled[node] {
+			status.Tasks = append(status.Tasks, e.getSkippedTaskStatus(node, deps, reason))
+		}
+	}
+}
 
-						return nil
-					}
-				}(node))
-			}
+func (e *pipelineCtx) getSkippedTaskStatus(taskName string, deps []string, reason commonv1alpha1.Error) commonv1alpha1.TaskExecutionStatus {
+	status, ok := e.lastExecution[taskName]
+	if !ok {
+		status = commonv1alpha1.TaskExecutionStatus{
+			Name:    taskName,
+			Depends: deps,
 		}
+	}
 
-		err := eg.Wait()
-		if err != nil {
-			e.logger.Error("find errors in execution...", zap.Error(err))
-			cErr := customerror.Unwrap(err)
-			status.Error = commonv1alpha1.Error{
-				Message: cErr.Message,
-				Code:    cErr.Code,
-				Tip:     cErr.Tip,
+	status.Status = TaskSkippedStatus
+	status.Error = reason
+	status.StartedAt = ""
+	status.FinishedAt = ""
+	status.Attempts = nil
+	status.Plan = commonv1alpha1.TaskPlanStatus{}
+	status.Approval = nil
+	status.Hooks = nil
+
+	return status
+}
+
+// getTransitiveDependents returns every node that depends directly or indirectly on the given node.
+func getTransitiveDependents(graph map[string][]string, node string) []string {
+	dependents := []string{}
+	visited := map[string]bool{node: true}
+	queue := []string{node}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for n, deps := range graph {
+			if visited[n] {
+				continue
 			}
-			status.Status = InfraErrorStatus
-			statusChan <- status
-			return
-		}
 
-		if len(e.executionContext) == len(graph) {
-			e.logger.Info("executed all tasks successfully")
-			status.Status = InfraSuccessStatus
-			statusChan <- status
-			return
+			for _, dep := range deps {
+				if dep == current {
+					visited[n] = true
+					dependents = append(dependents, n)
+					queue = append(queue, n)
+					break
+				}
+			}
 		}
 	}
+
+	return dependents
+}
+
+func isTaskFailed(taskStatus commonv1alpha1.TaskExecutionStatus) bool {
+	switch taskStatus.Status {
+	case TaskApplyErrorStatus, TaskDestroyErrorStatus, TaskPlanErrorStatus, TaskTimeoutStatus:
+		return true
+	}
+
+	return false
+}
+
+// sendStatus publishes the execution status, runs without listeners (e.g. the
+// destroy of removed tasks before an apply) have a nil channel.
+func sendStatus(statusChan chan commonv1alpha1.ExecutionStatus, status commonv1alpha1.ExecutionStatus) {
+	if statusChan == nil {
+		return
+	}
+
+	statusChan <- status
 }
 
 func (p *pipelineCtx) apply(infra commonv1alpha1.Infra) ActionFuncType {
-	return func(taskName string, executionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+	return func(ctx context.Context, taskName string, executionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
 		p.logger.Info("applying task", zap.String("name", taskName))
 		lastTaskExecutionStatus := commonv1alpha1.TaskExecutionStatus{}
 		currentTask := commonv1alpha1.InfraTask{}
@@ -190,6 +553,34 @@ func (p *pipelineCtx) apply(infra commonv1alpha1.Infra) ActionFuncType {
 			StartedAt:   time.Now().Format(time.RFC3339),
 		}
 
+		executionCtx := ctx
+		ctx, cancel, err := getTaskContext(ctx, currentTask)
+		if err != nil {
+			status.Error = getInvalidTaskTimeoutError(err)
+			status.Status = TaskApplyErrorStatus
+			return status, nil
+		}
+		defer func() { cancel() }()
+
+		retry, err := newRetryPolicy(currentTask)
+		if err != nil {
+			status.Error = getInvalidRetryPolicyError(err)
+			status.Status = TaskApplyErrorStatus
+			return status, nil
+		}
+
+		enabled, err := p.evaluateTaskCondition(currentTask, executionContext)
+		if err != nil {
+			status.Task = lastTaskExecutionStatus.Task
+			status.Error = getTaskConditionError(taskName, err)
+			status.Status = TaskApplyErrorStatus
+			return status, nil
+		}
+
+		if !enabled {
+			return p.skipTaskByCondition(ctx, infra, currentTask, lastTaskExecutionStatus)
+		}
+
 		interpolatedInputs, err := p.interpolateTaskInputsByExecutionContext(currentTask, executionContext)
 		if err != nil {
 			status.Error = commonv1alpha1.Error{
@@ -203,16 +594,115 @@ func (p *pipelineCtx) apply(infra commonv1alpha1.Infra) ActionFuncType {
 
 		status.Inputs = interpolatedInputs
 		if currentTask.Backend == backend.TerraformBackend {
+			credentials, err := p.getSourceCredentials(currentTask.Terraform)
+			if err != nil {
+				status.Task = lastTaskExecutionStatus.Task
+				status.Error = getSourceCredentialsError(taskName, err)
+				status.Status = TaskApplyErrorStatus
+				return status, nil
+			}
+
+			sourceDigest, err := p.backend.Terraform.ResolveSource(ctx, currentTask.Terraform.Source, credentials)
+			if err != nil {
+				status.Task = lastTaskExecutionStatus.Task
+				status.Error = commonv1alpha1.Error{
+					Message: err.Error(),
+					Code:    "TASK_SOURCE_RESOLUTION_ERROR",
+					Tip:     fmt.Sprintf("Verify that the source of task %s is valid and reachable", taskName),
+				}
+				status.Status = TaskApplyErrorStatus
+				return status, nil
+			}
+
+			fingerprint, err := getTaskFingerprint(sourceDigest, currentTask, interpolatedInputs, executionContext)
+			if err != nil {
+				status.Task = lastTaskExecutionStatus.Task
+				status.Error = commonv1alpha1.Error{
+					Message: err.Error(),
+					Code:    "TASK_FINGERPRINT_ERROR",
+					Tip:     fmt.Sprintf("Verify that the inputs of task %s are valid", taskName),
+				}
+				status.Status = TaskApplyErrorStatus
+				return status, nil
+			}
+
+			if p.options.ReconcileMode != ForceReconcileMode && isTaskUnchanged(lastTaskExecutionStatus, fingerprint) {
+				unchangedStatus, outputs, ok := p.getUnchangedTaskStatus(ctx, currentTask, lastTaskExecutionStatus, interpolatedInputs, credentials)
+				if ok {
+					unchangedStatus.Task.Hooks = currentTask.Hooks
+					unchangedStatus.Task.DeletionPolicy = currentTask.DeletionPolicy
+					return unchangedStatus, outputs
+				}
+			}
+
+			if currentTask.RequiresApproval {
+				waitingStatus := status
+				waitingStatus.Task = lastTaskExecutionStatus.Task
+				status.Approval, err = p.waitTaskApproval(executionCtx, infra, waitingStatus, executionContext)
+				if err != nil {
+					status.Task = lastTaskExecutionStatus.Task
+					status.Error, status.Status = getTaskApprovalError(executionCtx, taskName, err)
+					status.FinishedAt = time.Now().Format(time.RFC3339)
+					return status, nil
+				}
+
+				// the time waiting for the approval doesn't count in the task timeout
+				cancel()
+				ctx, cancel, _ = getTaskContext(executionCtx, currentTask)
+			}
+
+			status.Hooks, err = p.runHooks(ctx, PreApplyHookPhase, currentTask.Hooks.PreApply, interpolatedInputs, nil)
+			if err != nil {
+				status.Task = lastTaskExecutionStatus.Task
+				status.Error = getTaskHookError(err)
+				status.Status = TaskApplyErrorStatus
+				status.FinishedAt = time.Now().Format(time.RFC3339)
+				return status, nil
+			}
+
 			applyInput := terraform.TerraformApplyInput{
 				Source:           currentTask.Terraform.Source,
 				Version:          currentTask.Terraform.Version,
 				TaskInputs:       interpolatedInputs,
 				PreviousState:    lastTaskExecutionStatus.Task.State,
 				PreviousLockDeps: lastTaskExecutionStatus.Task.DependencyLock,
+				Credentials:      credentials,
+				// applies the same revision used by the fingerprint
+				Revision: sourceDigest,
 			}
-			result, err := p.backend.Terraform.Apply(applyInput)
+			var result terraform.TerraformApplyResult
+			status.Attempts, err = retry.run(ctx, func() error {
+				var err error
+				result, err = p.backend.Terraform.Apply(ctx, applyInput)
+				if err != nil && result.State != "" {
+					// the next attempts must start from the resources created until the failure
+					applyInput.PreviousState = result.State
+					applyInput.PreviousLockDeps = result.DependenciesLock
+				}
+
+				return err
+			})
 			status.FinishedAt = time.Now().Format(time.RFC3339)
 			if err != nil {
+				// keeps the state of a partial apply to not lose track of created resources
+				status.Task = lastTaskExecutionStatus.Task
+				if applyInput.PreviousState != lastTaskExecutionStatus.Task.State {
+					status.Task = commonv1alpha1.TaskStatus{
+						Terraform:      currentTask.Terraform,
+						State:          applyInput.PreviousState,
+						DependencyLock: applyInput.PreviousLockDeps,
+						SourceRevision: result.SourceRevision,
+						Hooks:          currentTask.Hooks,
+						DeletionPolicy: currentTask.DeletionPolicy,
+					}
+				}
+
+				if ctx.Err() == context.DeadlineExceeded {
+					status.Error = getTaskTimeoutError(taskName)
+					status.Status = TaskTimeoutStatus
+					return status, nil
+				}
+
 				status.Error = commonv1alpha1.Error{
 					Message: err.Error(),
 					Code:    "TASK_APPLY_TERRAFORM_ERROR",
@@ -226,6 +716,10 @@ func (p *pipelineCtx) apply(infra commonv1alpha1.Infra) ActionFuncType {
 				Terraform:      currentTask.Terraform,
 				State:          result.State,
 				DependencyLock: result.DependenciesLock,
+				Fingerprint:    fingerprint,
+				SourceRevision: result.SourceRevision,
+				Hooks:          currentTask.Hooks,
+				DeletionPolicy: currentTask.DeletionPolicy,
 			}
 
 			outputs := map[string]ExecutionOutputItem{}
@@ -238,6 +732,17 @@ func (p *pipelineCtx) apply(infra commonv1alpha1.Infra) ActionFuncType {
 				}
 			}
 
+			// the task keeps the applied state when the post hooks fail, so the
+			// next execution applies it again
+			postHooks, err := p.runHooks(ctx, PostApplyHookPhase, currentTask.Hooks.PostApply, interpolatedInputs, outputs)
+			status.Hooks = append(status.Hooks, postHooks...)
+			if err != nil {
+				status.Error = getTaskHookError(err)
+				status.Status = TaskApplyErrorStatus
+				status.FinishedAt = time.Now().Format(time.RFC3339)
+				return status, nil
+			}
+
 			p.logger.Info("creating tasks outputs...")
 			err = p.createTaskOutputs(infra, currentTask, outputs)
 			if err != nil {
@@ -264,7 +769,7 @@ func (p *pipelineCtx) apply(infra commonv1alpha1.Infra) ActionFuncType {
 	}
 }
 
-func (p pipelineCtx) createTaskOutputs(infra commonv1alpha1.Infra, task commonv1alpha1.InfraTask, outputs map[string]ExecutionOutputItem) error {
+func (p *pipelineCtx) createTaskOutputs(infra commonv1alpha1.Infra, task commonv1alpha1.InfraTask, outputs map[string]ExecutionOutputItem) error {
 	for _, t := range task.TaskOutputs {
 		p.logger.Info("creating task output", zap.String("name", t.Name))
 		items := []taskoutput.RPCCreateTaskOutputItem{}
@@ -275,6 +780,7 @@ func (p pipelineCtx) createTaskOutputs(infra commonv1alpha1.Infra, task commonv1
 					Sensitive: value.Sensitive,
 				},
 				Value: value.Value,
+				Type:  value.Type,
 			})
 		}
 
@@ -298,32 +804,95 @@ func (p pipelineCtx) createTaskOutputs(infra commonv1alpha1.Infra, task commonv1
 }
 
 func (p *pipelineCtx) destroy(infra commonv1alpha1.Infra) ActionFuncType {
-	return func(taskName string, exectionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+	return func(ctx context.Context, taskName string, exectionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
 		lastTaskExecutionStatus := commonv1alpha1.TaskExecutionStatus{}
 		for _, e := range infra.Status.LastExecution.Tasks {
 			if e.Name == taskName {
 				lastTaskExecutionStatus = e
 			}
 		}
+		// tasks removed from the spec run the hooks of their last apply
+		currentTask := commonv1alpha1.InfraTask{Name: taskName, Hooks: lastTaskExecutionStatus.Task.Hooks}
+		for _, specTask := range infra.Spec.Tasks {
+			if specTask.Name == taskName {
+				currentTask = specTask
+				break
+			}
+		}
+
+		if getDeletionPolicy(infra, lastTaskExecutionStatus) == RetainDeletionPolicy {
+			return p.retain(infra, lastTaskExecutionStatus)
+		}
 		status := commonv1alpha1.TaskExecutionStatus{
 			Name:        lastTaskExecutionStatus.Name,
 			Depends:     lastTaskExecutionStatus.Depends,
+			Backend:     lastTaskExecutionStatus.Backend,
 			Inputs:      lastTaskExecutionStatus.Inputs,
 			TaskOutputs: lastTaskExecutionStatus.TaskOutputs,
 			Status:      TaskDestroyed,
 			StartedAt:   time.Now().Format(time.RFC3339),
 		}
 
+		ctx, cancel, err := getTaskContext(ctx, currentTask)
+		if err != nil {
+			status.Task = lastTaskExecutionStatus.Task
+			status.Error = getInvalidTaskTimeoutError(err)
+			status.Status = TaskDestroyErrorStatus
+			return status, nil
+		}
+		defer cancel()
+
+		retry, err := newRetryPolicy(currentTask)
+		if err != nil {
+			status.Task = lastTaskExecutionStatus.Task
+			status.Error = getInvalidRetryPolicyError(err)
+			status.Status = TaskDestroyErrorStatus
+			return status, nil
+		}
+
 		if lastTaskExecutionStatus.Backend == backend.TerraformBackend {
+			outputs, err := getLastKnownOutputs(lastTaskExecutionStatus)
+			if err != nil {
+				p.logger.Info("failed to read last known outputs for destroy hooks", zap.String("name", taskName), zap.Error(err))
+			}
+
+			status.Hooks, err = p.runHooks(ctx, PreDestroyHookPhase, currentTask.Hooks.PreDestroy, lastTaskExecutionStatus.Inputs, outputs)
+			if err != nil {
+				status.Task = lastTaskExecutionStatus.Task
+				status.Error = getTaskHookError(err)
+				status.Status = TaskDestroyErrorStatus
+				return status, nil
+			}
+
+			credentials, err := p.getSourceCredentials(lastTaskExecutionStatus.Task.Terraform)
+			if err != nil {
+				status.Task = lastTaskExecutionStatus.Task
+				status.Error = getSourceCredentialsError(taskName, err)
+				status.Status = TaskDestroyErrorStatus
+				return status, nil
+			}
+
+			// destroys with the same revision of the source that was applied
 			destroyInput := terraform.TerraformDestroyInput{
 				Source:           lastTaskExecutionStatus.Task.Source,
 				Version:          lastTaskExecutionStatus.Task.Version,
 				TaskInputs:       lastTaskExecutionStatus.Inputs,
 				PreviousState:    lastTaskExecutionStatus.Task.State,
 				PreviousLockDeps: lastTaskExecutionStatus.Task.DependencyLock,
+				Credentials:      credentials,
+				Revision:         lastTaskExecutionStatus.Task.SourceRevision,
 			}
-			err := p.backend.Terraform.Destroy(destroyInput)
+			status.Attempts, err = retry.run(ctx, func() error {
+				return p.backend.Terraform.Destroy(ctx, destroyInput)
+			})
 			if err != nil {
+				status.Task = lastTaskExecutionStatus.Task
+				if ctx.Err() == context.DeadlineExceeded {
+					status.Error = getTaskTimeoutError(taskName)
+					status.Status = TaskTimeoutStatus
+					return status, nil
+				}
+
 				status.Error = commonv1alpha1.Error{
 					Message: err.Error(),
 					Code:    "TASK_DESTROY_TERRAFORM_ERROR",
@@ -332,7 +901,18 @@ func (p *pipelineCtx) destroy(infra commonv1alpha1.Infra) ActionFuncType {
 				return status, nil
 			}
 
-			err = p.deleteTaskOutputs(lastTaskExecutionStatus)
+			// the state is kept when the post hooks fail, so the next executions
+			// destroy the task and run its hooks again
+			postHooks, err := p.runHooks(ctx, PostDestroyHookPhase, currentTask.Hooks.PostDestroy, lastTaskExecutionStatus.Inputs, outputs)
+			status.Hooks = append(status.Hooks, postHooks...)
+			if err != nil {
+				status.Task = lastTaskExecutionStatus.Task
+				status.Error = getTaskHookError(err)
+				status.Status = TaskDestroyErrorStatus
+				return status, nil
+			}
+
+			err = p.deleteTaskOutputs(infra.Namespace, lastTaskExecutionStatus)
 			if err != nil {
 				status.Error = commonv1alpha1.Error{
 					Message: err.Error(),
@@ -356,12 +936,12 @@ func (p *pipelineCtx) destroy(infra commonv1alpha1.Infra) ActionFuncType {
 	}
 }
 
-func (p pipelineCtx) deleteTaskOutputs(task commonv1alpha1.TaskExecutionStatus) error {
+func (p *pipelineCtx) deleteTaskOutputs(namespace string, task commonv1alpha1.TaskExecutionStatus) error {
 	var reply int
 	for _, t := range task.TaskOutputs {
 		err := p.rpcClient.Call("TaskOutputRPCHandler.DeleteTaskOutput", taskoutput.RPCCreateTaskOutputArgs{
 			Name:      t.Name,
-			Namespace: "default",
+			Namespace: namespace,
 		}, &reply)
 		if err != nil {
 			return err
@@ -371,7 +951,7 @@ func (p pipelineCtx) deleteTaskOutputs(task commonv1alpha1.TaskExecutionStatus)
 	return nil
 }
 
-func (e pipelineCtx) diffTasksForApply(infra commonv1alpha1.Infra) map[string]commonv1alpha1.TaskExecutionStatus {
+func (e *pipelineCtx) diffTasksForApply(infra commonv1alpha1.Infra) map[string]commonv1alpha1.TaskExecutionStatus {
 	forDeletion := map[string]commonv1alpha1.TaskExecutionStatus{}
 	for _, lastTaskExecution := range infra.Status.LastExecution.Tasks {
 		foundTask := false
@@ -382,7 +962,8 @@ func (e pipelineCtx) diffTasksForApply(infra commonv1alpha1.Infra) map[string]co
 			}
 		}
 
-		if !foundTask {
+		// destroyed, retained and disabled tasks have no resources left to destroy
+		if !foundTask && lastTaskExecution.Status != TaskDestroyed && lastTaskExecution.Status != TaskRetainedStatus && lastTaskExecution.Status != TaskConditionSkippedStatus {
 			forDeletion[lastTaskExecution.Name] = lastTaskExecution
 		}
 	}
@@ -399,15 +980,22 @@ func (p *pipelineCtx) getApplyGraph(infra commonv1alpha1.Infra) map[string][]str
 	return dependencyGraphForApply
 }
 
-func (p *pipelineCtx) getDestroyGraph(tasksForDestroy map[string]commonv1alpha1.TaskExecutionStatus) map[string][]string {
+// getDestroyGraph reverses the dependencies, a task is destroyed after all its
+// dependents. Retained tasks don't touch their resources, so they neither wait
+// nor are waited for.
+func (p *pipelineCtx) getDestroyGraph(infra commonv1alpha1.Infra, tasksForDestroy map[string]commonv1alpha1.TaskExecutionStatus) map[string][]string {
 	dependencyGraphForDeletion := map[string][]string{}
 	for task := range tasksForDestroy {
 		dependencyGraphForDeletion[task] = []string{}
 	}
 
 	for task, execution := range tasksForDestroy {
+		if getDeletionPolicy(infra, execution) == RetainDeletionPolicy {
+			continue
+		}
+
 		for _, dep := range execution.Depends {
-			if _, ok := tasksForDestroy[dep]; ok {
+			if depExecution, ok := tasksForDestroy[dep]; ok && getDeletionPolicy(infra, depExecution) != RetainDeletionPolicy {
 				dependencyGraphForDeletion[dep] = append(dependencyGraphForDeletion[dep], task)
 			}
 		}
@@ -415,3 +1003,25 @@ func (p *pipelineCtx) getDestroyGraph(tasksForDestroy map[string]commonv1alpha1.
 
 	return dependencyGraphForDeletion
 }
+
+// getInvalidInfraStatus fails the execution before any task runs, the tasks
+// of the last execution are kept so that their states are not lost.
+func getInvalidInfraStatus(action string, infra commonv1alpha1.Infra, err customerror.CustomError) commonv1alpha1.ExecutionStatus {
+	now := time.Now().Format(time.RFC3339)
+	status := commonv1alpha1.ExecutionStatus{
+		Status:     InfraErrorStatus,
+		StartedAt:  now,
+		FinishedAt: now,
+		Error: commonv1alpha1.Error{
+			Message: err.Message,
+			Code:    err.Code,
+			Tip:     err.Tip,
+		},
+	}
+
+	if action != PlanAction {
+		status.Tasks = infra.Status.LastExecution.Tasks
+	}
+
+	return status
+}
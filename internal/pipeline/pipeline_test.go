@@ -0,0 +1,689 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/approval"
+	"github.com/octopipe/cloudx/internal/backend"
+	"github.com/octopipe/cloudx/internal/backend/terraform"
+	"github.com/octopipe/cloudx/internal/statearchive"
+	"github.com/octopipe/cloudx/internal/taskoutput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+type PipelineTestSuite struct {
+	suite.Suite
+	pipeline *pipelineCtx
+}
+
+func (suite *PipelineTestSuite) SetupTest() {
+	logger, _ := zap.NewDevelopment()
+	suite.pipeline = &pipelineCtx{logger: logger, executionContext: make(ExecutionContext)}
+}
+
+// runAndCollect executes the graph and returns the last status published.
+func (suite *PipelineTestSuite) runAndCollect(graph map[string][]string, action ActionFuncType) commonv1alpha1.ExecutionStatus {
+	statusChan := make(chan commonv1alpha1.ExecutionStatus)
+	go func() {
+		suite.pipeline.Run(context.Background(), graph, action, nil, statusChan)
+		close(statusChan)
+	}()
+
+	last := commonv1alpha1.ExecutionStatus{}
+	for status := range statusChan {
+		last = status
+	}
+
+	return last
+}
+
+func (suite *PipelineTestSuite) TestRunRespectsMaxParallelTasks() {
+	suite.pipeline.maxParallelTasks = 2
+	graph := map[string][]string{"a": {}, "b": {}, "c": {}, "d": {}, "e": {}}
+
+	mu := sync.Mutex{}
+	running, maxRunning := 0, 0
+	action := func(ctx context.Context, taskName string, executionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return commonv1alpha1.TaskExecutionStatus{Name: taskName, Status: TaskAppliedStatus}, nil
+	}
+
+	status := suite.runAndCollect(graph, action)
+	assert.Equal(suite.T(), InfraSuccessStatus, status.Status)
+	assert.Len(suite.T(), status.Tasks, 5)
+	assert.Equal(suite.T(), 2, maxRunning)
+}
+
+func (suite *PipelineTestSuite) TestRunKeepsDependencyOrder() {
+	suite.pipeline.maxParallelTasks = 4
+	graph := map[string][]string{"vpc": {}, "subnet": {"vpc"}, "cluster": {"subnet", "vpc"}}
+
+	action := func(ctx context.Context, taskName string, executionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+		for _, dep := range graph[taskName] {
+			_, ok := executionContext[dep]
+			assert.True(suite.T(), ok, "task %s started before its dependency %s", taskName, dep)
+		}
+
+		return commonv1alpha1.TaskExecutionStatus{Name: taskName, Status: TaskAppliedStatus}, map[string]ExecutionOutputItem{}
+	}
+
+	status := suite.runAndCollect(graph, action)
+	assert.Equal(suite.T(), InfraSuccessStatus, status.Status)
+	assert.Equal(suite.T(), []string{"vpc", "subnet", "cluster"}, []string{status.Tasks[0].Name, status.Tasks[1].Name, status.Tasks[2].Name})
+}
+
+func (suite *PipelineTestSuite) TestRunStopsSchedulingOnFailure() {
+	suite.pipeline.maxParallelTasks = 1
+	graph := map[string][]string{"a": {}, "b": {"a"}}
+
+	action := func(ctx context.Context, taskName string, executionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+		return commonv1alpha1.TaskExecutionStatus{Name: taskName, Status: TaskApplyErrorStatus, Error: commonv1alpha1.Error{Code: "FAILED"}}, nil
+	}
+
+	status := suite.runAndCollect(graph, action)
+	assert.Equal(suite.T(), InfraErrorStatus, status.Status)
+	assert.Equal(suite.T(), "FAILED", status.Error.Code)
+	assert.Equal(suite.T(), TaskSkippedStatus, status.Tasks[1].Status)
+}
+
+func (suite *PipelineTestSuite) TestRunContinuesIndependentTasks() {
+	suite.pipeline.maxParallelTasks = 1
+	suite.pipeline.failurePolicy = ContinueIndependentFailurePolicy
+	graph := map[string][]string{"vpc": {}, "subnet": {"vpc"}, "cluster": {"subnet"}, "bucket": {}, "cdn": {"bucket"}}
+
+	mu := sync.Mutex{}
+	executed := []string{}
+	action := func(ctx context.Context, taskName string, executionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+		mu.Lock()
+		executed = append(executed, taskName)
+		mu.Unlock()
+
+		if taskName == "vpc" {
+			return commonv1alpha1.TaskExecutionStatus{Name: taskName, Status: TaskApplyErrorStatus, Error: commonv1alpha1.Error{Code: "FAILED"}}, nil
+		}
+
+		return commonv1alpha1.TaskExecutionStatus{Name: taskName, Status: TaskAppliedStatus}, map[string]ExecutionOutputItem{}
+	}
+
+	status := suite.runAndCollect(graph, action)
+	assert.Equal(suite.T(), InfraErrorStatus, status.Status)
+	assert.Equal(suite.T(), "FAILED", status.Error.Code)
+	assert.Equal(suite.T(), []string{"bucket", "vpc", "cdn"}, executed)
+	assert.Len(suite.T(), status.Tasks, 5)
+	assert.Equal(suite.T(), TaskAppliedStatus, getTestTaskStatus(status, "cdn").Status)
+
+	// the transitive dependents of the failed task are skipped
+	for _, name := range []string{"subnet", "cluster"} {
+		task := getTestTaskStatus(status, name)
+		assert.Equal(suite.T(), TaskSkippedStatus, task.Status, name)
+		assert.Equal(suite.T(), "TASK_DEPENDENCY_FAILED", task.Error.Code, name)
+		assert.Equal(suite.T(), "skipped because the task vpc failed", task.Error.Message, name)
+	}
+}
+
+func (suite *PipelineTestSuite) TestTargetedApplyGraph() {
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc"},
+		commonv1alpha1.InfraTask{Name: "subnet", Depends: []string{"vpc"}},
+		commonv1alpha1.InfraTask{Name: "cluster", Depends: []string{"subnet"}},
+		commonv1alpha1.InfraTask{Name: "bucket"},
+	)
+
+	graph, err := suite.pipeline.getTargetedApplyGraph(infra, []string{"subnet"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string][]string{"vpc": nil, "subnet": {"vpc"}}, graph)
+
+	_, err = suite.pipeline.getTargetedApplyGraph(infra, []string{"database"})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *PipelineTestSuite) TestTasksForTargetedDestroy() {
+	infra := commonv1alpha1.Infra{}
+	infra.Status.LastExecution.Tasks = []commonv1alpha1.TaskExecutionStatus{
+		{Name: "vpc"},
+		{Name: "subnet", Depends: []string{"vpc"}},
+		{Name: "cluster", Depends: []string{"subnet"}},
+		{Name: "bucket"},
+	}
+	suite.pipeline.lastExecution = map[string]commonv1alpha1.TaskExecutionStatus{}
+	for _, t := range infra.Status.LastExecution.Tasks {
+		suite.pipeline.lastExecution[t.Name] = t
+	}
+
+	tasksForDestroy, err := suite.pipeline.getTasksForTargetedDestroy(infra, []string{"subnet"})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), tasksForDestroy, 2)
+	assert.Contains(suite.T(), tasksForDestroy, "subnet")
+	assert.Contains(suite.T(), tasksForDestroy, "cluster")
+
+	retainedTasks := suite.pipeline.getRetainedTasks(infra, suite.pipeline.getDestroyGraph(infra, tasksForDestroy))
+	assert.Equal(suite.T(), []string{"vpc", "bucket"}, []string{retainedTasks[0].Name, retainedTasks[1].Name})
+}
+
+func (suite *PipelineTestSuite) TestResumedTasks() {
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc", Backend: "terraform", Inputs: []commonv1alpha1.InfraTaskInput{{Key: "cidr", Value: "10.0.0.0/16"}}},
+		commonv1alpha1.InfraTask{Name: "bucket", Backend: "terraform"},
+		commonv1alpha1.InfraTask{
+			Name:    "subnet",
+			Backend: "terraform",
+			Depends: []string{"vpc"},
+			Inputs:  []commonv1alpha1.InfraTaskInput{{Key: "vpc_id", Value: "{{ this.vpc.id }}"}},
+		},
+		commonv1alpha1.InfraTask{Name: "cluster", Backend: "terraform", Depends: []string{"subnet"}},
+	)
+	infra.Status.LastExecution.Tasks = []commonv1alpha1.TaskExecutionStatus{
+		{
+			Name:    "vpc",
+			Backend: "terraform",
+			Status:  TaskAppliedStatus,
+			Inputs:  []commonv1alpha1.InfraTaskInput{{Key: "cidr", Value: "10.0.0.0/16"}},
+			Outputs: []commonv1alpha1.TaskExecutionOutput{{Key: "id", Value: "\"vpc-123\""}},
+		},
+		{Name: "bucket", Backend: "terraform", Status: TaskApplyErrorStatus},
+		{
+			Name:    "subnet",
+			Backend: "terraform",
+			Depends: []string{"vpc"},
+			Status:  TaskAppliedStatus,
+			Inputs:  []commonv1alpha1.InfraTaskInput{{Key: "vpc_id", Value: "vpc-123"}},
+		},
+		{Name: "cluster", Backend: "terraform", Depends: []string{"subnet"}, Status: TaskSkippedStatus},
+	}
+	suite.pipeline.lastExecution = map[string]commonv1alpha1.TaskExecutionStatus{}
+	for _, t := range infra.Status.LastExecution.Tasks {
+		suite.pipeline.lastExecution[t.Name] = t
+	}
+
+	resumedTasks := suite.pipeline.getResumedTasks(infra)
+	assert.Equal(suite.T(), []string{"vpc", "subnet"}, []string{resumedTasks[0].Name, resumedTasks[1].Name})
+
+	// a changed dependency must execute its dependents again
+	infra.Spec.Tasks[0].Inputs[0].Value = "10.1.0.0/16"
+	resumedTasks = suite.pipeline.getResumedTasks(infra)
+	assert.Empty(suite.T(), resumedTasks)
+}
+
+func (suite *PipelineTestSuite) TestTaskCondition() {
+	executionContext := ExecutionContext{"network": {"bastion_enabled": {Value: "false"}, "replica": {Value: "\"True\""}}}
+
+	enabled, err := suite.pipeline.evaluateTaskCondition(commonv1alpha1.InfraTask{Name: "bastion"}, executionContext)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), enabled)
+
+	enabled, err = suite.pipeline.evaluateTaskCondition(commonv1alpha1.InfraTask{Name: "bastion", When: "{{ this.network.bastion_enabled }}"}, executionContext)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), enabled)
+
+	enabled, err = suite.pipeline.evaluateTaskCondition(commonv1alpha1.InfraTask{Name: "replica", When: "{{ this.network.replica }}"}, executionContext)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), enabled)
+
+	_, err = suite.pipeline.evaluateTaskCondition(commonv1alpha1.InfraTask{Name: "bastion", When: "maybe"}, executionContext)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *PipelineTestSuite) TestExpandTasks() {
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{
+			Name: "sns",
+			ForEach: []commonv1alpha1.InfraTaskForEachItem{
+				{Key: "bank", Value: "topic-bank"},
+				{Value: "global"},
+				{Key: "raw", Value: "{{ this.final.id }}"},
+			},
+			Inputs: []commonv1alpha1.InfraTaskInput{{Key: "name", Value: "{{ each.key }}-{{each.value}}"}},
+		},
+		commonv1alpha1.InfraTask{Name: "final", Depends: []string{"sns"}},
+	)
+
+	expandedInfra, instances, err := suite.pipeline.expandTasks(infra)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.pipeline.validateInfra(expandedInfra))
+	assert.Equal(suite.T(), map[string][]string{"sns": {"sns-bank", "sns-global", "sns-raw"}}, instances)
+	assert.Len(suite.T(), expandedInfra.Spec.Tasks, 4)
+	assert.Equal(suite.T(), "sns-bank", expandedInfra.Spec.Tasks[0].Name)
+	assert.Equal(suite.T(), []string{"sns-bank", "sns-global", "sns-raw"}, expandedInfra.Spec.Tasks[3].Depends)
+
+	// the items are resolved when the inputs are interpolated, values are
+	// never parsed as expressions
+	for i, expected := range []string{"bank-topic-bank", "global-global", "raw-{{ this.final.id }}"} {
+		inputs, err := suite.pipeline.interpolateTaskInputsByExecutionContext(expandedInfra.Spec.Tasks[i], ExecutionContext{})
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), expected, inputs[0].Value)
+	}
+
+	infra.Spec.Tasks[0].ForEach = append(infra.Spec.Tasks[0].ForEach, commonv1alpha1.InfraTaskForEachItem{Key: "bank"})
+	_, _, err = suite.pipeline.expandTasks(infra)
+	assert.Error(suite.T(), err)
+}
+
+// startAndCollect starts the pipeline and returns the last status published.
+func (suite *PipelineTestSuite) startAndCollect(action string, infra commonv1alpha1.Infra, options ExecutionOptions) commonv1alpha1.ExecutionStatus {
+	statusChan := make(chan commonv1alpha1.ExecutionStatus)
+	go func() {
+		suite.pipeline.Start(context.Background(), action, infra, options, statusChan)
+		close(statusChan)
+	}()
+
+	last := commonv1alpha1.ExecutionStatus{}
+	for status := range statusChan {
+		last = status
+	}
+
+	return last
+}
+
+func getTestTaskStatus(status commonv1alpha1.ExecutionStatus, name string) commonv1alpha1.TaskExecutionStatus {
+	for _, t := range status.Tasks {
+		if t.Name == name {
+			return t
+		}
+	}
+
+	return commonv1alpha1.TaskExecutionStatus{}
+}
+
+// fakeTerraformBackend runs the given functions instead of terraform, the
+// unset ones succeed without changes.
+type fakeTerraformBackend struct {
+	apply   func(ctx context.Context, input terraform.TerraformApplyInput) (terraform.TerraformApplyResult, error)
+	destroy func(ctx context.Context, input terraform.TerraformDestroyInput) error
+	plan    func(ctx context.Context, input terraform.TerraformPlanInput) (terraform.TerraformPlanResult, error)
+}
+
+func (b fakeTerraformBackend) Apply(ctx context.Context, input terraform.TerraformApplyInput) (terraform.TerraformApplyResult, error) {
+	if b.apply == nil {
+		return terraform.TerraformApplyResult{State: "state"}, nil
+	}
+
+	return b.apply(ctx, input)
+}
+
+func (b fakeTerraformBackend) Destroy(ctx context.Context, input terraform.TerraformDestroyInput) error {
+	if b.destroy == nil {
+		return nil
+	}
+
+	return b.destroy(ctx, input)
+}
+
+func (b fakeTerraformBackend) Plan(ctx context.Context, input terraform.TerraformPlanInput) (terraform.TerraformPlanResult, error) {
+	if b.plan == nil {
+		return terraform.TerraformPlanResult{}, nil
+	}
+
+	return b.plan(ctx, input)
+}
+
+func (b fakeTerraformBackend) ResolveSource(ctx context.Context, source string, credentials map[string][]byte) (string, error) {
+	return source, nil
+}
+
+func TestPipelineTestSuite(t *testing.T) {
+	suite.Run(t, new(PipelineTestSuite))
+}
+
+type fakeRPCClient struct {
+	taskOutputs map[string]commonv1alpha1.TaskOutput
+}
+
+func (c fakeRPCClient) Call(method string, args any, reply any) error {
+	ref := args.(taskoutput.RPCGetTaskOutputArgs).Ref
+	taskOutput, ok := c.taskOutputs[ref.String()]
+	if !ok {
+		return fmt.Errorf("not found task output %s", ref.String())
+	}
+
+	*reply.(*commonv1alpha1.TaskOutput) = taskOutput
+	return nil
+}
+
+func (suite *PipelineTestSuite) TestInterpolateStructuredOutputs() {
+	suite.pipeline.namespace = "team-a"
+	suite.pipeline.rpcClient = fakeRPCClient{taskOutputs: map[string]commonv1alpha1.TaskOutput{
+		"network/vpc": {Spec: commonv1alpha1.TaskOutputSpec{Outputs: []commonv1alpha1.TaskOutputSpecItem{
+			{Key: "subnet_ids", Value: &apiextensionsv1.JSON{Raw: []byte(`["subnet-a","subnet-b"]`)}},
+		}}},
+	}}
+
+	executionContext := ExecutionContext{
+		"db": {
+			"endpoint": {Value: `{"host":"db.local","port":5432}`, Type: `["object",{"host":"string","port":"number"}]`},
+			"password": {Value: `"secret"`, Type: `"string"`, Sensitive: true},
+		},
+	}
+
+	value, sensitive, err := suite.pipeline.interpolateValue("app", "url", "{{ this.db.endpoint.host }}:{{ this.db.endpoint.port }}", executionContext)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "db.local:5432", value)
+	assert.False(suite.T(), sensitive)
+
+	value, sensitive, err = suite.pipeline.interpolateValue("app", "password", "{{ this.db.password }}", executionContext)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "secret", value)
+	assert.True(suite.T(), sensitive)
+
+	value, _, err = suite.pipeline.interpolateValue("app", "subnet", "{{ task-output.network/vpc.subnet_ids[1] }}", executionContext)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "subnet-b", value)
+
+	_, _, err = suite.pipeline.interpolateValue("app", "subnet", "{{ task-output.vpc.subnet_ids }}", executionContext)
+	assert.EqualError(suite.T(), err, "input subnet: column 4: not found task output team-a/vpc")
+}
+
+func (suite *PipelineTestSuite) TestNestedObjectInput() {
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc"},
+		commonv1alpha1.InfraTask{
+			Name:    "cluster",
+			Depends: []string{"vpc"},
+			Inputs: []commonv1alpha1.InfraTaskInput{
+				{Key: "settings", Value: `{"tags":{"env":"dev"}}`, Type: "json"},
+				{Key: "network", Value: `{"vpc":{"id":"{{ this.vpc.id }}"}}`},
+			},
+		},
+	)
+	assert.NoError(suite.T(), suite.pipeline.validateInfra(infra))
+
+	executionContext := ExecutionContext{"vpc": {"id": {Value: `"vpc-1"`, Type: `"string"`}}}
+	value, _, err := suite.pipeline.interpolateValue("app", "settings", `{"tags":{"env":"dev"}}`, executionContext)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"tags":{"env":"dev"}}`, value)
+
+	value, _, err = suite.pipeline.interpolateValue("app", "network", `{"vpc":{"id":"{{ this.vpc.id }}"}}`, executionContext)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"vpc":{"id":"vpc-1"}}`, value)
+}
+
+func (suite *PipelineTestSuite) TestRunPublishesWaitingApproval() {
+	graph := map[string][]string{"vpc": {}, "cluster": {"vpc"}}
+	action := func(ctx context.Context, taskName string, executionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+		if taskName == "cluster" {
+			suite.pipeline.notifyWaitingApproval(commonv1alpha1.TaskExecutionStatus{Name: taskName, Status: TaskWaitingApprovalStatus})
+		}
+
+		return commonv1alpha1.TaskExecutionStatus{Name: taskName, Status: TaskAppliedStatus}, map[string]ExecutionOutputItem{}
+	}
+
+	statusChan := make(chan commonv1alpha1.ExecutionStatus)
+	go func() {
+		suite.pipeline.Run(context.Background(), graph, action, nil, statusChan)
+		close(statusChan)
+	}()
+
+	statuses := []commonv1alpha1.ExecutionStatus{}
+	for status := range statusChan {
+		statuses = append(statuses, status)
+	}
+
+	assert.Len(suite.T(), statuses, 4)
+	assert.Equal(suite.T(), InfraWaitingApprovalStatus, statuses[1].Status)
+	assert.Equal(suite.T(), []string{"vpc", "cluster"}, []string{statuses[1].Tasks[0].Name, statuses[1].Tasks[1].Name})
+	assert.Equal(suite.T(), TaskWaitingApprovalStatus, statuses[1].Tasks[1].Status)
+	assert.Equal(suite.T(), InfraSuccessStatus, statuses[3].Status)
+	assert.Equal(suite.T(), TaskAppliedStatus, statuses[3].Tasks[1].Status)
+}
+
+// fakeApprovalRPCClient approves the task on the second poll, tasks are never
+// approved without approver.
+type fakeApprovalRPCClient struct {
+	calls    *int
+	approver string
+}
+
+func (c fakeApprovalRPCClient) Call(method string, args any, reply any) error {
+	*c.calls++
+	if *c.calls > 1 && c.approver != "" {
+		*reply.(*commonv1alpha1.TaskApproval) = commonv1alpha1.TaskApproval{
+			Task:       args.(approval.RPCGetTaskApprovalArgs).Task,
+			Approver:   c.approver,
+			ApprovedAt: "2023-01-01T00:00:00Z",
+		}
+	}
+
+	return nil
+}
+
+func (suite *PipelineTestSuite) TestWaitTaskApproval() {
+	defaultApprovalPollInterval := approvalPollInterval
+	approvalPollInterval = time.Millisecond
+	suite.T().Cleanup(func() { approvalPollInterval = defaultApprovalPollInterval })
+	calls := 0
+	suite.pipeline.rpcClient = fakeApprovalRPCClient{calls: &calls, approver: "jane"}
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc", RequiresApproval: true},
+		commonv1alpha1.InfraTask{Name: "cluster", Depends: []string{"vpc"}},
+		commonv1alpha1.InfraTask{Name: "bucket"},
+	)
+
+	waiting := make(chan commonv1alpha1.TaskExecutionStatus, 1)
+	suite.pipeline.waitingApprovalChan = waiting
+	taskApproval, err := suite.pipeline.waitTaskApproval(context.Background(), infra, commonv1alpha1.TaskExecutionStatus{Name: "vpc"}, ExecutionContext{})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, calls)
+	assert.Equal(suite.T(), "jane", taskApproval.Approver)
+	assert.Equal(suite.T(), "2023-01-01T00:00:00Z", taskApproval.ApprovedAt)
+	assert.Equal(suite.T(), []string{"vpc", "cluster"}, []string{taskApproval.Plans[0].Name, taskApproval.Plans[1].Name})
+
+	waitingStatus := <-waiting
+	assert.Equal(suite.T(), TaskWaitingApprovalStatus, waitingStatus.Status)
+	assert.Len(suite.T(), waitingStatus.Approval.Plans, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	suite.pipeline.rpcClient = fakeApprovalRPCClient{calls: &calls}
+	suite.pipeline.waitingApprovalChan = nil
+	_, err = suite.pipeline.waitTaskApproval(ctx, infra, commonv1alpha1.TaskExecutionStatus{Name: "vpc"}, ExecutionContext{})
+	assert.ErrorIs(suite.T(), err, context.DeadlineExceeded)
+	taskErr, taskStatus := getTaskApprovalError(ctx, "vpc", err)
+	assert.Equal(suite.T(), "TASK_APPROVAL_TIME_LIMIT_EXCEEDED", taskErr.Code)
+	assert.Equal(suite.T(), TaskTimeoutStatus, taskStatus)
+}
+
+type fakeArchiveRPCClient struct {
+	archived map[string]string
+}
+
+func (c fakeArchiveRPCClient) Call(method string, args any, reply any) error {
+	archiveArgs := args.(statearchive.RPCArchiveStateArgs)
+	c.archived[archiveArgs.TaskName] = archiveArgs.Task.State
+	*reply.(*commonv1alpha1.Ref) = commonv1alpha1.Ref{Name: archiveArgs.TaskName + "-state-x1", Namespace: archiveArgs.InfraRef.Namespace}
+	return nil
+}
+
+func (suite *PipelineTestSuite) TestDeletionPolicy() {
+	infra := newTestInfra(commonv1alpha1.InfraTask{Name: "db", DeletionPolicy: RetainDeletionPolicy})
+	infra.Namespace = "team-a"
+	infra.Status.LastExecution.Tasks = []commonv1alpha1.TaskExecutionStatus{
+		{Name: "vpc", Status: TaskAppliedStatus, Backend: "terraform", Task: commonv1alpha1.TaskStatus{State: "vpc-state", DeletionPolicy: RetainDeletionPolicy}},
+		{Name: "subnet", Depends: []string{"vpc"}, Status: TaskAppliedStatus, Task: commonv1alpha1.TaskStatus{State: "subnet-state"}},
+		{Name: "db", Depends: []string{"subnet"}, Status: TaskAppliedStatus, Task: commonv1alpha1.TaskStatus{State: "db-state"}},
+		{Name: "app", Depends: []string{"subnet"}, Status: TaskAppliedStatus, Task: commonv1alpha1.TaskStatus{State: "app-state"}},
+		{Name: "bucket", Status: TaskDestroyed},
+	}
+
+	tasksForDestroy := suite.pipeline.diffTasksForApply(commonv1alpha1.Infra{Status: infra.Status})
+	assert.Len(suite.T(), tasksForDestroy, 4)
+
+	// the spec policy of db wins over the policy of its last apply
+	assert.Equal(suite.T(), map[string][]string{"vpc": {}, "subnet": {"app"}, "db": {}, "app": {}}, suite.pipeline.getDestroyGraph(infra, tasksForDestroy))
+
+	archived := map[string]string{}
+	suite.pipeline.rpcClient = fakeArchiveRPCClient{archived: archived}
+	status, _ := suite.pipeline.destroy(infra)(context.Background(), "vpc", ExecutionContext{})
+	assert.Equal(suite.T(), TaskRetainedStatus, status.Status)
+	assert.Equal(suite.T(), "vpc-state", status.Task.State)
+	assert.Equal(suite.T(), &commonv1alpha1.Ref{Name: "vpc-state-x1", Namespace: "team-a"}, status.Task.ArchivedState)
+	assert.Equal(suite.T(), map[string]string{"vpc": "vpc-state"}, archived)
+
+	infra.Status.LastExecution.Tasks[0] = status
+	assert.NotContains(suite.T(), suite.pipeline.diffTasksForApply(infra), "vpc")
+}
+
+func (suite *PipelineTestSuite) TestDestroyInfra() {
+	destroyed := []string{}
+	mu := sync.Mutex{}
+	suite.pipeline.backend = backend.NewBackend(fakeTerraformBackend{
+		destroy: func(ctx context.Context, input terraform.TerraformDestroyInput) error {
+			mu.Lock()
+			defer mu.Unlock()
+			destroyed = append(destroyed, input.PreviousState)
+			return nil
+		},
+	})
+	archived := map[string]string{}
+	suite.pipeline.rpcClient = fakeArchiveRPCClient{archived: archived}
+
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc", Backend: "terraform", DeletionPolicy: RetainDeletionPolicy},
+		commonv1alpha1.InfraTask{Name: "app", Backend: "terraform", Depends: []string{"vpc"}},
+	)
+	infra.Namespace = "team-a"
+	infra.Status.LastExecution.Tasks = []commonv1alpha1.TaskExecutionStatus{
+		{Name: "vpc", Status: TaskAppliedStatus, Backend: "terraform", Task: commonv1alpha1.TaskStatus{State: "vpc-state"}},
+		{Name: "app", Depends: []string{"vpc"}, Status: TaskAppliedStatus, Backend: "terraform", Task: commonv1alpha1.TaskStatus{State: "app-state"}},
+		{Name: "removed", Status: TaskAppliedStatus, Backend: "terraform", Task: commonv1alpha1.TaskStatus{State: "removed-state"}},
+	}
+
+	// deleting the infra destroys every task of the last execution, except
+	// the retained ones which are archived
+	status := suite.startAndCollect(DestroyAction, infra, ExecutionOptions{})
+	assert.Equal(suite.T(), InfraSuccessStatus, status.Status)
+	assert.Equal(suite.T(), TaskRetainedStatus, getTestTaskStatus(status, "vpc").Status)
+	assert.Equal(suite.T(), TaskDestroyed, getTestTaskStatus(status, "app").Status)
+	assert.Equal(suite.T(), TaskDestroyed, getTestTaskStatus(status, "removed").Status)
+	assert.ElementsMatch(suite.T(), []string{"app-state", "removed-state"}, destroyed)
+	assert.Equal(suite.T(), map[string]string{"vpc": "vpc-state"}, archived)
+}
+
+func (suite *PipelineTestSuite) TestPlanUnknownOutputs() {
+	planned := []string{}
+	mu := sync.Mutex{}
+	suite.pipeline.backend = backend.NewBackend(fakeTerraformBackend{
+		plan: func(ctx context.Context, input terraform.TerraformPlanInput) (terraform.TerraformPlanResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			planned = append(planned, input.Source)
+			if input.Source != "vpc" {
+				return terraform.TerraformPlanResult{}, nil
+			}
+
+			return terraform.TerraformPlanResult{Add: 1, Outputs: map[string]terraform.PlannedOutput{
+				"name": {OutputMeta: tfexec.OutputMeta{Value: []byte(`"main"`), Type: []byte(`"string"`)}},
+				"id":   {Unknown: true},
+			}}, nil
+		},
+	})
+
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc", Backend: "terraform", Terraform: commonv1alpha1.Terraform{Source: "vpc"}},
+		commonv1alpha1.InfraTask{
+			Name: "subnet", Backend: "terraform", Depends: []string{"vpc"}, Terraform: commonv1alpha1.Terraform{Source: "subnet"},
+			Inputs: []commonv1alpha1.InfraTaskInput{{Key: "vpc_id", Value: "{{ this.vpc.id }}"}},
+		},
+		commonv1alpha1.InfraTask{
+			Name: "cluster", Backend: "terraform", Depends: []string{"subnet"}, Terraform: commonv1alpha1.Terraform{Source: "cluster"},
+			Inputs: []commonv1alpha1.InfraTaskInput{{Key: "subnet_id", Value: "{{ this.subnet.id }}"}},
+		},
+		commonv1alpha1.InfraTask{
+			Name: "dns", Backend: "terraform", Depends: []string{"vpc"}, Terraform: commonv1alpha1.Terraform{Source: "dns"},
+			Inputs: []commonv1alpha1.InfraTaskInput{{Key: "zone", Value: "{{ this.vpc.name }}.local"}},
+		},
+	)
+
+	status := suite.startAndCollect(PlanAction, infra, ExecutionOptions{})
+	assert.Equal(suite.T(), InfraSuccessStatus, status.Status)
+	assert.ElementsMatch(suite.T(), []string{"vpc", "dns"}, planned)
+
+	vpc := getTestTaskStatus(status, "vpc")
+	assert.Equal(suite.T(), []commonv1alpha1.TaskExecutionOutput{
+		{Key: "id", Unknown: true},
+		{Key: "name", Value: `"main"`, Type: `"string"`},
+	}, vpc.Outputs)
+
+	// the unknown id is propagated to the dependents of subnet
+	for _, name := range []string{"subnet", "cluster"} {
+		task := getTestTaskStatus(status, name)
+		assert.Equal(suite.T(), TaskPlannedStatus, task.Status, name)
+		assert.Contains(suite.T(), task.Plan.Rendered, "is known after apply", name)
+		assert.Empty(suite.T(), task.Outputs, name)
+	}
+
+	dns := getTestTaskStatus(status, "dns")
+	assert.Equal(suite.T(), TaskPlannedStatus, dns.Status)
+	assert.Equal(suite.T(), "main.local", dns.Inputs[0].Value)
+}
+
+// blockingTerraformBackend blocks the applies until their context is done.
+func blockingTerraformBackend(canceled chan<- string) backend.Backend {
+	return backend.NewBackend(fakeTerraformBackend{
+		apply: func(ctx context.Context, input terraform.TerraformApplyInput) (terraform.TerraformApplyResult, error) {
+			<-ctx.Done()
+			canceled <- input.Source
+			return terraform.TerraformApplyResult{}, ctx.Err()
+		},
+	})
+}
+
+func (suite *PipelineTestSuite) TestTaskTimeout() {
+	canceled := make(chan string, 1)
+	suite.pipeline.backend = blockingTerraformBackend(canceled)
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "slow", Backend: "terraform", Timeout: "20ms", Terraform: commonv1alpha1.Terraform{Source: "slow"}},
+		commonv1alpha1.InfraTask{Name: "app", Backend: "terraform", Depends: []string{"slow"}},
+	)
+
+	status := suite.startAndCollect(ApplyAction, infra, ExecutionOptions{})
+	assert.Equal(suite.T(), InfraErrorStatus, status.Status)
+	assert.Equal(suite.T(), "slow", <-canceled)
+
+	slow := getTestTaskStatus(status, "slow")
+	assert.Equal(suite.T(), TaskTimeoutStatus, slow.Status)
+	assert.Equal(suite.T(), "TASK_TIME_LIMIT_EXCEEDED", slow.Error.Code)
+	assert.Equal(suite.T(), TaskSkippedStatus, getTestTaskStatus(status, "app").Status)
+}
+
+func (suite *PipelineTestSuite) TestExecutionTimeout() {
+	canceled := make(chan string, 2)
+	suite.pipeline.backend = blockingTerraformBackend(canceled)
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc", Backend: "terraform", Terraform: commonv1alpha1.Terraform{Source: "vpc"}},
+		commonv1alpha1.InfraTask{Name: "bucket", Backend: "terraform", Terraform: commonv1alpha1.Terraform{Source: "bucket"}},
+		commonv1alpha1.InfraTask{Name: "app", Backend: "terraform", Depends: []string{"vpc"}},
+	)
+	infra.Spec.ExecutionTimeout = "30ms"
+
+	start := time.Now()
+	status := suite.startAndCollect(ApplyAction, infra, ExecutionOptions{})
+	assert.Less(suite.T(), time.Since(start), 5*time.Second)
+	assert.Equal(suite.T(), InfraTimeoutStatus, status.Status)
+	assert.Equal(suite.T(), "TIME_LIMIT_EXCEEDED", status.Error.Code)
+
+	// the deadline cancels every running task, the others are not started
+	assert.ElementsMatch(suite.T(), []string{"vpc", "bucket"}, []string{<-canceled, <-canceled})
+	assert.Equal(suite.T(), TaskTimeoutStatus, getTestTaskStatus(status, "vpc").Status)
+	assert.Equal(suite.T(), TaskTimeoutStatus, getTestTaskStatus(status, "bucket").Status)
+	assert.Equal(suite.T(), TaskSkippedStatus, getTestTaskStatus(status, "app").Status)
+	assert.Equal(suite.T(), "TIME_LIMIT_EXCEEDED", getTestTaskStatus(status, "app").Error.Code)
+}
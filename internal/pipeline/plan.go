@@ -0,0 +1,256 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/backend"
+	"github.com/octopipe/cloudx/internal/backend/terraform"
+	"github.com/octopipe/cloudx/internal/expression"
+	"go.uber.org/zap"
+)
+
+// unknownOutputsKey holds the outputs of a task that couldn't be planned, all
+// of them are unknown to its dependents.
+const unknownOutputsKey = "*"
+
+func (p *pipelineCtx) plan(infra commonv1alpha1.Infra, tasksForDestroy map[string]commonv1alpha1.TaskExecutionStatus) ActionFuncType {
+	return func(ctx context.Context, taskName string, executionContext ExecutionContext) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+		if lastTaskExecutionStatus, ok := tasksForDestroy[taskName]; ok {
+			return p.planRelease(ctx, infra, lastTaskExecutionStatus)
+		}
+
+		p.logger.Info("planning task", zap.String("name", taskName))
+		lastTaskExecutionStatus := commonv1alpha1.TaskExecutionStatus{}
+		currentTask := commonv1alpha1.InfraTask{}
+		for _, specTask := range infra.Spec.Tasks {
+			if specTask.Name == taskName {
+				currentTask = specTask
+				break
+			}
+		}
+		for _, e := range infra.Status.LastExecution.Tasks {
+			if e.Name == currentTask.Name {
+				lastTaskExecutionStatus = e
+			}
+		}
+
+		status := commonv1alpha1.TaskExecutionStatus{
+			Name:        currentTask.Name,
+			Depends:     currentTask.Depends,
+			Inputs:      currentTask.Inputs,
+			Backend:     currentTask.Backend,
+			TaskOutputs: currentTask.TaskOutputs,
+			Status:      TaskPlannedStatus,
+			StartedAt:   time.Now().Format(time.RFC3339),
+		}
+
+		ctx, cancel, err := getTaskContext(ctx, currentTask)
+		if err != nil {
+			status.Error = getInvalidTaskTimeoutError(err)
+			status.Status = TaskPlanErrorStatus
+			return status, nil
+		}
+		defer cancel()
+
+		retry, err := newRetryPolicy(currentTask)
+		if err != nil {
+			status.Error = getInvalidRetryPolicyError(err)
+			status.Status = TaskPlanErrorStatus
+			return status, nil
+		}
+
+		enabled, err := p.evaluateTaskCondition(currentTask, executionContext)
+		if errors.As(err, &expression.UnknownError{}) {
+			return getUnknownInputsPlanStatus(status, currentTask, err)
+		}
+		if err != nil {
+			status.Error = getTaskConditionError(taskName, err)
+			status.Status = TaskPlanErrorStatus
+			return status, nil
+		}
+
+		if !enabled {
+			if lastTaskExecutionStatus.Task.State != "" {
+				return p.planRelease(ctx, infra, lastTaskExecutionStatus)
+			}
+
+			status.Status = TaskConditionSkippedStatus
+			status.FinishedAt = time.Now().Format(time.RFC3339)
+			return status, map[string]ExecutionOutputItem{}
+		}
+
+		interpolatedInputs, err := p.interpolateTaskInputsByExecutionContext(currentTask, executionContext)
+		if errors.As(err, &expression.UnknownError{}) {
+			return getUnknownInputsPlanStatus(status, currentTask, err)
+		}
+		if err != nil {
+			status.Error = commonv1alpha1.Error{
+				Message: err.Error(),
+				Code:    "TASK_INPUT_INTERPOLATION_ERROR",
+				Tip:     "Verify that the task inputs are valid",
+			}
+			status.Status = TaskPlanErrorStatus
+			return status, nil
+		}
+
+		status.Inputs = interpolatedInputs
+		if currentTask.Backend != backend.TerraformBackend {
+			status.Error = commonv1alpha1.Error{
+				Message: "invalid task backend",
+				Code:    "INVALID_TASK_BACKEND",
+				Tip:     "Verify that the task backend is valid",
+			}
+			status.Status = TaskPlanErrorStatus
+			return status, nil
+		}
+
+		credentials, err := p.getSourceCredentials(currentTask.Terraform)
+		if err != nil {
+			status.Error = getSourceCredentialsError(taskName, err)
+			status.Status = TaskPlanErrorStatus
+			return status, nil
+		}
+
+		planInput := terraform.TerraformPlanInput{
+			Source:           currentTask.Terraform.Source,
+			Version:          currentTask.Terraform.Version,
+			TaskInputs:       interpolatedInputs,
+			PreviousState:    lastTaskExecutionStatus.Task.State,
+			PreviousLockDeps: lastTaskExecutionStatus.Task.DependencyLock,
+			Credentials:      credentials,
+		}
+		var result terraform.TerraformPlanResult
+		status.Attempts, err = retry.run(ctx, func() error {
+			var err error
+			result, err = p.backend.Terraform.Plan(ctx, planInput)
+			return err
+		})
+		status.FinishedAt = time.Now().Format(time.RFC3339)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				status.Error = getTaskTimeoutError(taskName)
+				status.Status = TaskTimeoutStatus
+				return status, nil
+			}
+
+			status.Error = commonv1alpha1.Error{
+				Message: err.Error(),
+				Code:    "TASK_PLAN_TERRAFORM_ERROR",
+				Tip:     fmt.Sprintf("Verify that the terraform code of task %s is valid", taskName),
+			}
+			status.Status = TaskPlanErrorStatus
+			return status, nil
+		}
+
+		status.Task = commonv1alpha1.TaskStatus{
+			Terraform: currentTask.Terraform,
+		}
+		status.Plan = commonv1alpha1.TaskPlanStatus{
+			Add:      result.Add,
+			Change:   result.Change,
+			Destroy:  result.Destroy,
+			Rendered: result.Rendered,
+		}
+
+		outputs := map[string]ExecutionOutputItem{}
+		for key, tfMeta := range result.Outputs {
+			outputs[key] = ExecutionOutputItem{
+				Value:     string(tfMeta.Value),
+				Type:      string(tfMeta.Type),
+				Sensitive: tfMeta.Sensitive,
+				Unknown:   tfMeta.Unknown,
+			}
+		}
+
+		return status, outputs
+	}
+}
+
+// getUnknownInputsPlanStatus reports a task whose inputs or condition use
+// values only known after the apply, terraform can't plan it until then. The
+// unknown values are propagated: every output of the task is unknown.
+func getUnknownInputsPlanStatus(status commonv1alpha1.TaskExecutionStatus, currentTask commonv1alpha1.InfraTask, err error) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+	status.Status = TaskPlannedStatus
+	status.FinishedAt = time.Now().Format(time.RFC3339)
+	status.Task = commonv1alpha1.TaskStatus{
+		Terraform: currentTask.Terraform,
+	}
+	status.Plan = commonv1alpha1.TaskPlanStatus{
+		Rendered: fmt.Sprintf("task %s can only be planned after the apply of its dependencies: %s", currentTask.Name, err.Error()),
+	}
+
+	return status, map[string]ExecutionOutputItem{unknownOutputsKey: {Unknown: true}}
+}
+
+func (p *pipelineCtx) planDestroy(ctx context.Context, lastTaskExecutionStatus commonv1alpha1.TaskExecutionStatus) (commonv1alpha1.TaskExecutionStatus, map[string]ExecutionOutputItem) {
+	p.logger.Info("planning task destroy", zap.String("name", lastTaskExecutionStatus.Name))
+	status := commonv1alpha1.TaskExecutionStatus{
+		Name:        lastTaskExecutionStatus.Name,
+		Depends:     lastTaskExecutionStatus.Depends,
+		Inputs:      lastTaskExecutionStatus.Inputs,
+		Backend:     lastTaskExecutionStatus.Backend,
+		TaskOutputs: lastTaskExecutionStatus.TaskOutputs,
+		Status:      TaskPlannedStatus,
+		StartedAt:   time.Now().Format(time.RFC3339),
+	}
+
+	if lastTaskExecutionStatus.Backend != backend.TerraformBackend {
+		status.Error = commonv1alpha1.Error{
+			Message: "invalid task backend",
+			Code:    "INVALID_TASK_BACKEND",
+			Tip:     "Verify that the task backend is valid",
+		}
+		status.Status = TaskPlanErrorStatus
+		return status, nil
+	}
+
+	credentials, err := p.getSourceCredentials(lastTaskExecutionStatus.Task.Terraform)
+	if err != nil {
+		status.Error = getSourceCredentialsError(lastTaskExecutionStatus.Name, err)
+		status.Status = TaskPlanErrorStatus
+		return status, nil
+	}
+
+	result, err := p.backend.Terraform.Plan(ctx, terraform.TerraformPlanInput{
+		Source:           lastTaskExecutionStatus.Task.Source,
+		Version:          lastTaskExecutionStatus.Task.Version,
+		TaskInputs:       lastTaskExecutionStatus.Inputs,
+		PreviousState:    lastTaskExecutionStatus.Task.State,
+		PreviousLockDeps: lastTaskExecutionStatus.Task.DependencyLock,
+		Credentials:      credentials,
+		Revision:         lastTaskExecutionStatus.Task.SourceRevision,
+		Destroy:          true,
+	})
+	status.FinishedAt = time.Now().Format(time.RFC3339)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			status.Error = getTaskTimeoutError(lastTaskExecutionStatus.Name)
+			status.Status = TaskTimeoutStatus
+			return status, nil
+		}
+
+		status.Error = commonv1alpha1.Error{
+			Message: err.Error(),
+			Code:    "TASK_PLAN_TERRAFORM_ERROR",
+			Tip:     fmt.Sprintf("Verify that the terraform code of task %s is valid", lastTaskExecutionStatus.Name),
+		}
+		status.Status = TaskPlanErrorStatus
+		return status, nil
+	}
+
+	status.Task = commonv1alpha1.TaskStatus{
+		Terraform: lastTaskExecutionStatus.Task.Terraform,
+	}
+	status.Plan = commonv1alpha1.TaskPlanStatus{
+		Add:      result.Add,
+		Change:   result.Change,
+		Destroy:  result.Destroy,
+		Rendered: result.Rendered,
+	}
+
+	return status, map[string]ExecutionOutputItem{}
+}
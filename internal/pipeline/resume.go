@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"reflect"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"go.uber.org/zap"
+)
+
+// getResumedTasks returns the tasks of the last execution that don't need to be
+// executed again: they succeeded, their spec didn't change and all their
+// dependencies are resumed as well. The execution restarts from the others.
+func (p *pipelineCtx) getResumedTasks(infra commonv1alpha1.Infra) []commonv1alpha1.TaskExecutionStatus {
+	specTasks := map[string]commonv1alpha1.InfraTask{}
+	for _, t := range infra.Spec.Tasks {
+		specTasks[t.Name] = t
+	}
+
+	resumed := map[string]bool{}
+	visited := map[string]bool{}
+	executionContext := ExecutionContext{}
+	var isResumed func(name string) bool
+	isResumed = func(name string) bool {
+		if visited[name] {
+			return resumed[name]
+		}
+		visited[name] = true
+
+		currentTask := specTasks[name]
+		last, ok := p.lastExecution[name]
+		if !ok || (last.Status != TaskAppliedStatus && last.Status != TaskUnchangedStatus) {
+			return false
+		}
+
+		for _, dep := range currentTask.Depends {
+			if !isResumed(dep) {
+				return false
+			}
+		}
+
+		if !p.isTaskSpecUnchanged(currentTask, last, executionContext) {
+			return false
+		}
+
+		outputs, err := getLastKnownOutputs(last)
+		if err != nil {
+			p.logger.Info("failed to read last known outputs, task will be executed again", zap.String("name", name), zap.Error(err))
+			return false
+		}
+
+		executionContext[name] = outputs
+		resumed[name] = true
+		return true
+	}
+
+	resumedTasks := []commonv1alpha1.TaskExecutionStatus{}
+	for _, t := range infra.Spec.Tasks {
+		if isResumed(t.Name) {
+			resumedTasks = append(resumedTasks, p.lastExecution[t.Name])
+		}
+	}
+
+	return resumedTasks
+}
+
+// isTaskSpecUnchanged compares the spec of a task with its last execution, the
+// inputs are interpolated with the outputs of the resumed dependencies.
+func (p *pipelineCtx) isTaskSpecUnchanged(currentTask commonv1alpha1.InfraTask, last commonv1alpha1.TaskExecutionStatus, executionContext ExecutionContext) bool {
+	if currentTask.Backend != last.Backend || currentTask.Terraform != last.Task.Terraform {
+		return false
+	}
+
+	if !isEqualSlice(currentTask.Depends, last.Depends) || !isEqualSlice(currentTask.TaskOutputs, last.TaskOutputs) {
+		return false
+	}
+
+	enabled, err := p.evaluateTaskCondition(currentTask, executionContext)
+	if err != nil || !enabled {
+		return false
+	}
+
+	inputs, err := p.interpolateTaskInputsByExecutionContext(currentTask, executionContext)
+	if err != nil {
+		return false
+	}
+
+	return isEqualSlice(inputs, last.Inputs)
+}
+
+// isEqualSlice compares two slices considering nil and empty slices equal, as
+// both are persisted the same way in the status.
+func isEqualSlice[T any](a []T, b []T) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,116 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+)
+
+const (
+	DefaultRetryBackoff    = 10 * time.Second
+	DefaultRetryMaxBackoff = 5 * time.Minute
+)
+
+type retryPolicy struct {
+	maxAttempts   int
+	backoff       *backoff.ExponentialBackOff
+	errorPatterns []*regexp.Regexp
+}
+
+func newRetryPolicy(task commonv1alpha1.InfraTask) (retryPolicy, error) {
+	policy := retryPolicy{maxAttempts: 1}
+	if task.Retry.MaxAttempts > 1 {
+		policy.maxAttempts = task.Retry.MaxAttempts
+	}
+
+	initialInterval := DefaultRetryBackoff
+	if task.Retry.Backoff != "" {
+		d, err := time.ParseDuration(task.Retry.Backoff)
+		if err != nil {
+			return retryPolicy{}, fmt.Errorf("invalid retry backoff %s for task %s: %w", task.Retry.Backoff, task.Name, err)
+		}
+		initialInterval = d
+	}
+
+	maxInterval := DefaultRetryMaxBackoff
+	if task.Retry.MaxBackoff != "" {
+		d, err := time.ParseDuration(task.Retry.MaxBackoff)
+		if err != nil {
+			return retryPolicy{}, fmt.Errorf("invalid retry max backoff %s for task %s: %w", task.Retry.MaxBackoff, task.Name, err)
+		}
+		maxInterval = d
+	}
+
+	for _, pattern := range task.Retry.ErrorPatterns {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return retryPolicy{}, fmt.Errorf("invalid retry error pattern %s for task %s: %w", pattern, task.Name, err)
+		}
+		policy.errorPatterns = append(policy.errorPatterns, r)
+	}
+
+	policy.backoff = backoff.NewExponentialBackOff()
+	policy.backoff.InitialInterval = initialInterval
+	policy.backoff.MaxInterval = maxInterval
+	policy.backoff.MaxElapsedTime = 0
+	policy.backoff.Reset()
+
+	return policy, nil
+}
+
+func (r retryPolicy) isRetryable(err error) bool {
+	if len(r.errorPatterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range r.errorPatterns {
+		if pattern.MatchString(err.Error()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// run executes the operation until it succeeds or the policy gives up, every
+// attempt is returned to be recorded in the task execution status.
+func (r retryPolicy) run(ctx context.Context, operation func() error) ([]commonv1alpha1.TaskAttemptStatus, error) {
+	attempts := []commonv1alpha1.TaskAttemptStatus{}
+	for attempt := 1; ; attempt++ {
+		attemptStatus := commonv1alpha1.TaskAttemptStatus{
+			Attempt:   attempt,
+			StartedAt: time.Now().Format(time.RFC3339),
+		}
+
+		err := operation()
+		attemptStatus.FinishedAt = time.Now().Format(time.RFC3339)
+		if err == nil {
+			attempts = append(attempts, attemptStatus)
+			return attempts, nil
+		}
+
+		attemptStatus.Error = commonv1alpha1.Error{Message: err.Error()}
+		attempts = append(attempts, attemptStatus)
+		if attempt >= r.maxAttempts || ctx.Err() != nil || !r.isRetryable(err) {
+			return attempts, err
+		}
+
+		select {
+		case <-time.After(r.backoff.NextBackOff()):
+		case <-ctx.Done():
+			return attempts, err
+		}
+	}
+}
+
+func getInvalidRetryPolicyError(err error) commonv1alpha1.Error {
+	return commonv1alpha1.Error{
+		Message: err.Error(),
+		Code:    "INVALID_TASK_RETRY_POLICY",
+		Tip:     "Verify that the retry backoffs are valid durations and the error patterns are valid regular expressions",
+	}
+}
@@ -0,0 +1,131 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRetryPolicy(t *testing.T) {
+	cases := map[string]struct {
+		retry           commonv1alpha1.InfraTaskRetry
+		maxAttempts     int
+		initialInterval time.Duration
+		maxInterval     time.Duration
+		err             string
+	}{
+		"defaults": {
+			maxAttempts:     1,
+			initialInterval: DefaultRetryBackoff,
+			maxInterval:     DefaultRetryMaxBackoff,
+		},
+		"custom backoff": {
+			retry:           commonv1alpha1.InfraTaskRetry{MaxAttempts: 3, Backoff: "2s", MaxBackoff: "1m"},
+			maxAttempts:     3,
+			initialInterval: 2 * time.Second,
+			maxInterval:     time.Minute,
+		},
+		"invalid backoff": {
+			retry: commonv1alpha1.InfraTaskRetry{Backoff: "2 seconds"},
+			err:   "invalid retry backoff 2 seconds for task vpc",
+		},
+		"invalid max backoff": {
+			retry: commonv1alpha1.InfraTaskRetry{MaxBackoff: "forever"},
+			err:   "invalid retry max backoff forever for task vpc",
+		},
+		"invalid error pattern": {
+			retry: commonv1alpha1.InfraTaskRetry{ErrorPatterns: []string{"throttl(ing"}},
+			err:   "invalid retry error pattern throttl(ing for task vpc",
+		},
+	}
+
+	for name, c := range cases {
+		policy, err := newRetryPolicy(commonv1alpha1.InfraTask{Name: "vpc", Retry: c.retry})
+		if c.err != "" {
+			assert.ErrorContains(t, err, c.err, name)
+			continue
+		}
+
+		assert.NoError(t, err, name)
+		assert.Equal(t, c.maxAttempts, policy.maxAttempts, name)
+		assert.Equal(t, c.initialInterval, policy.backoff.InitialInterval, name)
+		assert.Equal(t, c.maxInterval, policy.backoff.MaxInterval, name)
+	}
+}
+
+func TestRetryErrorPatterns(t *testing.T) {
+	cases := map[string]struct {
+		patterns  []string
+		err       string
+		retryable bool
+	}{
+		"without patterns every error is retried": {err: "invalid resource", retryable: true},
+		"matching pattern":                        {patterns: []string{"Throttling", "RequestLimitExceeded"}, err: "api error RequestLimitExceeded: slow down", retryable: true},
+		"matching regular expression":             {patterns: []string{`status code: 5\d\d`}, err: "status code: 503", retryable: true},
+		"non-matching pattern":                    {patterns: []string{"Throttling"}, err: "invalid resource", retryable: false},
+	}
+
+	for name, c := range cases {
+		policy, err := newRetryPolicy(commonv1alpha1.InfraTask{Name: "vpc", Retry: commonv1alpha1.InfraTaskRetry{ErrorPatterns: c.patterns}})
+		assert.NoError(t, err, name)
+		assert.Equal(t, c.retryable, policy.isRetryable(errors.New(c.err)), name)
+	}
+}
+
+func TestRetryRun(t *testing.T) {
+	throttling := errors.New("Throttling: rate exceeded")
+	invalid := errors.New("invalid resource")
+	cases := map[string]struct {
+		errs     []error
+		attempts int
+		err      error
+	}{
+		"retryable error that succeeds":    {errs: []error{throttling, throttling, nil}, attempts: 3},
+		"non-matching error fails at once": {errs: []error{invalid, nil}, attempts: 1, err: invalid},
+		"stops at max attempts":            {errs: []error{throttling, throttling, throttling, throttling, nil}, attempts: 4, err: throttling},
+	}
+
+	for name, c := range cases {
+		policy, err := newRetryPolicy(commonv1alpha1.InfraTask{Name: "vpc", Retry: commonv1alpha1.InfraTaskRetry{
+			MaxAttempts:   4,
+			Backoff:       "1ms",
+			MaxBackoff:    "2ms",
+			ErrorPatterns: []string{"Throttling"},
+		}})
+		assert.NoError(t, err, name)
+
+		calls := 0
+		attempts, err := policy.run(context.Background(), func() error {
+			calls++
+			return c.errs[calls-1]
+		})
+		assert.Equal(t, c.err, err, name)
+		assert.Equal(t, c.attempts, calls, name)
+		assert.Len(t, attempts, c.attempts, name)
+		for i, a := range attempts {
+			assert.Equal(t, i+1, a.Attempt, name)
+		}
+
+		if c.err != nil {
+			assert.Equal(t, c.err.Error(), attempts[len(attempts)-1].Error.Message, name)
+		}
+	}
+}
+
+func TestRetryRunStopsOnCanceledContext(t *testing.T) {
+	policy, err := newRetryPolicy(commonv1alpha1.InfraTask{Name: "vpc", Retry: commonv1alpha1.InfraTaskRetry{MaxAttempts: 5, Backoff: "1h"}})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	attempts, err := policy.run(ctx, func() error {
+		return errors.New("timeout")
+	})
+	assert.EqualError(t, err, "timeout")
+	assert.Len(t, attempts, 1)
+}
@@ -0,0 +1,39 @@
+package pipeline
+
+import (
+	"fmt"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/variable"
+)
+
+// getSourceCredentials reads the secret with the credentials of a private task
+// source through the controller, only secrets of the infra namespace are allowed.
+func (p *pipelineCtx) getSourceCredentials(tf commonv1alpha1.Terraform) (map[string][]byte, error) {
+	if tf.CredentialsRef.Name == "" {
+		return nil, nil
+	}
+
+	if tf.CredentialsRef.Namespace != "" && tf.CredentialsRef.Namespace != p.namespace {
+		return nil, fmt.Errorf("the credentials of source %s must be in the infra namespace %s", tf.Source, p.namespace)
+	}
+
+	credentials := map[string][]byte{}
+	err := p.rpcClient.Call("VariableRPCHandler.GetSecretData", variable.RPCGetSecretDataArgs{
+		InfraRef: p.infraRef,
+		Name:     tf.CredentialsRef.Name,
+	}, &credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the credentials of source %s: %w", tf.Source, err)
+	}
+
+	return credentials, nil
+}
+
+func getSourceCredentialsError(taskName string, err error) commonv1alpha1.Error {
+	return commonv1alpha1.Error{
+		Message: err.Error(),
+		Code:    "TASK_SOURCE_CREDENTIALS_ERROR",
+		Tip:     fmt.Sprintf("Verify that the credentials secret of task %s exists in the infra namespace", taskName),
+	}
+}
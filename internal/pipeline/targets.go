@@ -0,0 +1,163 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/customerror"
+	"go.uber.org/zap"
+)
+
+// getTargetedApplyGraph prunes the apply graph to the targets and every task
+// they depend on.
+func (p *pipelineCtx) getTargetedApplyGraph(infra commonv1alpha1.Infra, targets []string) (map[string][]string, error) {
+	applyGraph := p.getApplyGraph(infra)
+	targetedGraph := map[string][]string{}
+	for _, target := range targets {
+		if _, ok := applyGraph[target]; !ok {
+			return nil, getInvalidTargetError(target)
+		}
+
+		targetedGraph[target] = applyGraph[target]
+		for dep := range getTransitiveDependencies(applyGraph, target) {
+			targetedGraph[dep] = applyGraph[dep]
+		}
+	}
+
+	return targetedGraph, nil
+}
+
+// getTasksForTargetedDestroy returns the targets and every task of the last
+// execution that depends on them, dependents can't outlive their dependencies.
+func (p *pipelineCtx) getTasksForTargetedDestroy(infra commonv1alpha1.Infra, targets []string) (map[string]commonv1alpha1.TaskExecutionStatus, error) {
+	lastExecutionGraph := map[string][]string{}
+	for _, t := range infra.Status.LastExecution.Tasks {
+		lastExecutionGraph[t.Name] = t.Depends
+	}
+
+	tasksForDestroy := map[string]commonv1alpha1.TaskExecutionStatus{}
+	for _, target := range targets {
+		if _, ok := lastExecutionGraph[target]; !ok {
+			return nil, getInvalidTargetError(target)
+		}
+
+		tasksForDestroy[target] = p.lastExecution[target]
+		for _, dependent := range getTransitiveDependents(lastExecutionGraph, target) {
+			tasksForDestroy[dependent] = p.lastExecution[dependent]
+		}
+	}
+
+	return tasksForDestroy, nil
+}
+
+// getRetainedTasks returns the tasks of the last execution that are not part of
+// the graph, they are kept untouched in the status of a targeted execution.
+func (p *pipelineCtx) getRetainedTasks(infra commonv1alpha1.Infra, graph map[string][]string) []commonv1alpha1.TaskExecutionStatus {
+	retainedTasks := []commonv1alpha1.TaskExecutionStatus{}
+	for _, t := range infra.Status.LastExecution.Tasks {
+		if _, ok := graph[t.Name]; !ok {
+			retainedTasks = append(retainedTasks, t)
+		}
+	}
+
+	return retainedTasks
+}
+
+// seedExecutionContext makes the last known outputs of the tasks available to
+// the tasks that are executed.
+func (p *pipelineCtx) seedExecutionContext(tasks []commonv1alpha1.TaskExecutionStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, t := range tasks {
+		outputs, err := getLastKnownOutputs(t)
+		if err != nil {
+			p.logger.Info("failed to read last known outputs", zap.String("name", t.Name), zap.Error(err))
+			continue
+		}
+
+		p.executionContext[t.Name] = outputs
+	}
+}
+
+// getLastKnownOutputs returns the outputs persisted with the task execution,
+// executions that didn't persist them fall back to the outputs of the state.
+// Sensitive values aren't persisted, they are always read from the state.
+func getLastKnownOutputs(t commonv1alpha1.TaskExecutionStatus) (map[string]ExecutionOutputItem, error) {
+	if len(t.Outputs) > 0 {
+		outputs := map[string]ExecutionOutputItem{}
+		var stateOutputs map[string]ExecutionOutputItem
+		for _, o := range t.Outputs {
+			value := o.Value
+			if o.Sensitive {
+				if stateOutputs == nil {
+					var err error
+					stateOutputs, err = getOutputsFromState(t.Task.State)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				stateOutput, ok := stateOutputs[o.Key]
+				if !ok {
+					return nil, fmt.Errorf("not found the sensitive output %s in the state of task %s", o.Key, t.Name)
+				}
+
+				value = stateOutput.Value
+			}
+
+			outputs[o.Key] = ExecutionOutputItem{
+				Value:     value,
+				Type:      o.Type,
+				Sensitive: o.Sensitive,
+			}
+		}
+
+		return outputs, nil
+	}
+
+	if t.Task.State == "" {
+		return map[string]ExecutionOutputItem{}, nil
+	}
+
+	return getOutputsFromState(t.Task.State)
+}
+
+func getTaskExecutionOutputs(outputs map[string]ExecutionOutputItem) []commonv1alpha1.TaskExecutionOutput {
+	executionOutputs := []commonv1alpha1.TaskExecutionOutput{}
+	for key, o := range outputs {
+		if key == unknownOutputsKey {
+			continue
+		}
+
+		// sensitive values are kept out of the infra status, they are read
+		// from the state when the outputs are needed again
+		value := o.Value
+		if o.Sensitive {
+			value = ""
+		}
+
+		executionOutputs = append(executionOutputs, commonv1alpha1.TaskExecutionOutput{
+			Key:       key,
+			Value:     value,
+			Type:      o.Type,
+			Sensitive: o.Sensitive,
+			Unknown:   o.Unknown,
+		})
+	}
+
+	sort.Slice(executionOutputs, func(i, j int) bool {
+		return executionOutputs[i].Key < executionOutputs[j].Key
+	})
+
+	return executionOutputs
+}
+
+func getInvalidTargetError(target string) error {
+	return customerror.New(
+		fmt.Sprintf("not found the target task %s", target),
+		"INVALID_TARGET",
+		"Verify that all targets are tasks of this infra",
+	)
+}
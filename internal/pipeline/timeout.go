@@ -0,0 +1,57 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+)
+
+const DefaultExecutionTimeout = 10 * time.Minute
+
+func getExecutionTimeout(infra commonv1alpha1.Infra) (time.Duration, error) {
+	if infra.Spec.ExecutionTimeout == "" {
+		return DefaultExecutionTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(infra.Spec.ExecutionTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid execution timeout %s: %w", infra.Spec.ExecutionTimeout, err)
+	}
+
+	return timeout, nil
+}
+
+// getTaskContext returns a context bounded by the task timeout, tasks without
+// timeout are only bounded by the execution context.
+func getTaskContext(ctx context.Context, task commonv1alpha1.InfraTask) (context.Context, context.CancelFunc, error) {
+	if task.Timeout == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+
+	timeout, err := time.ParseDuration(task.Timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timeout %s for task %s: %w", task.Timeout, task.Name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+func getTaskTimeoutError(taskName string) commonv1alpha1.Error {
+	return commonv1alpha1.Error{
+		Message: fmt.Sprintf("time limit exceeded for task %s", taskName),
+		Code:    "TASK_TIME_LIMIT_EXCEEDED",
+		Tip:     fmt.Sprintf("Verify if the task %s is not stuck or increase its timeout", taskName),
+	}
+}
+
+func getInvalidTaskTimeoutError(err error) commonv1alpha1.Error {
+	return commonv1alpha1.Error{
+		Message: err.Error(),
+		Code:    "INVALID_TASK_TIMEOUT",
+		Tip:     "Verify that the task timeout is a valid duration, e.g. 10m",
+	}
+}
@@ -1,15 +1,35 @@
 package pipeline
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
-	"github.com/octopipe/cloudx/internal/lex"
+	"github.com/octopipe/cloudx/internal/backend/terraform"
+	"github.com/octopipe/cloudx/internal/customerror"
+	"github.com/octopipe/cloudx/internal/expression"
 	"github.com/octopipe/cloudx/internal/task"
 )
 
-func (p pipelineCtx) validateDependencies(infra commonv1alpha1.Infra) error {
+func (p *pipelineCtx) validateTaskNames(infra commonv1alpha1.Infra) error {
+	names := map[string]bool{}
+	for _, t := range infra.Spec.Tasks {
+		if t.Name == "" {
+			return customerror.New("found a task without name", "INVALID_TASK_NAME", "Verify that all tasks have a name")
+		}
+
+		if names[t.Name] {
+			return customerror.New(fmt.Sprintf("duplicated task name %s", t.Name), "INVALID_TASK_NAME", "Verify that the task names are unique")
+		}
+
+		names[t.Name] = true
+	}
+
+	return nil
+}
+
+func (p *pipelineCtx) validateDependencies(infra commonv1alpha1.Infra) error {
 	graph := map[string][]string{}
 	for _, task := range infra.Spec.Tasks {
 		graph[task.Name] = task.Depends
@@ -18,7 +38,11 @@ func (p pipelineCtx) validateDependencies(infra commonv1alpha1.Infra) error {
 	for _, p := range infra.Spec.Tasks {
 		for _, dep := range p.Depends {
 			if _, ok := graph[dep]; !ok {
-				return fmt.Errorf("not found the dependency %s specified in task %s", dep, p.Name)
+				return customerror.New(
+					fmt.Sprintf("not found the dependency %s specified in task %s", dep, p.Name),
+					"INVALID_TASK_DEPENDENCY",
+					"Verify that all dependencies are tasks of this infra",
+				)
 			}
 		}
 	}
@@ -26,43 +50,256 @@ func (p pipelineCtx) validateDependencies(infra commonv1alpha1.Infra) error {
 	return nil
 }
 
-func (p pipelineCtx) validateInputInterpolations(infra commonv1alpha1.Infra) error {
+// validateCycles walks the dependency graph in depth and fails on the first
+// dependency that points back to a task in the current path.
+func (p *pipelineCtx) validateCycles(infra commonv1alpha1.Infra) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
 	graph := map[string][]string{}
 	for _, task := range infra.Spec.Tasks {
 		graph[task.Name] = task.Depends
 	}
 
-	for _, p := range infra.Spec.Tasks {
-		for _, i := range p.Inputs {
-			tokens := lex.Tokenize(i.Value)
-
-			for _, t := range tokens {
-				if t.Type == lex.TokenVariable {
-					s := strings.Split(strings.Trim(t.Value, " "), ".")
-					if len(s) != 3 {
-						return fmt.Errorf("malformed input variable %s with value %s", i.Key, i.Value)
+	state := map[string]int{}
+	path := []string{}
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		state[node] = visiting
+		path = append(path, node)
+		for _, dep := range graph[node] {
+			switch state[dep] {
+			case visiting:
+				for i, n := range path {
+					if n == dep {
+						cycle := append([]string{}, path[i:]...)
+						return append(cycle, dep)
 					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
 
-					origin, name := s[0], s[1]
-					if origin != task.ThisInterpolationOrigin && origin != task.TaskOutputInterpolationOrigin {
-						return fmt.Errorf("invalid origin: %s for input %s interpolation with value %s", origin, i.Key, i.Value)
-					}
+		path = path[:len(path)-1]
+		state[node] = visited
+		return nil
+	}
 
-					if origin == task.ThisInterpolationOrigin {
-						if _, ok := graph[name]; !ok {
-							return fmt.Errorf("invalid name: %s in origin this for input %s interpolation with value %s", name, i.Key, i.Value)
-						}
-					}
-				}
+	for _, task := range infra.Spec.Tasks {
+		if state[task.Name] != unvisited {
+			continue
+		}
+
+		if cycle := visit(task.Name); cycle != nil {
+			return customerror.New(
+				fmt.Sprintf("found a dependency cycle between tasks: %s", strings.Join(cycle, " -> ")),
+				"TASK_DEPENDENCY_CYCLE",
+				"Remove one of the dependencies of the cycle",
+			)
+		}
+	}
+
+	return nil
+}
+
+func (p *pipelineCtx) validateInputInterpolations(infra commonv1alpha1.Infra) error {
+	graph := map[string][]string{}
+	for _, task := range infra.Spec.Tasks {
+		graph[task.Name] = task.Depends
+	}
+
+	variables := map[string]bool{}
+	for _, v := range infra.Spec.Variables {
+		variables[v.Key] = true
+	}
+
+	for _, t := range infra.Spec.Tasks {
+		dependencies := getTransitiveDependencies(graph, t.Name)
+		for _, i := range t.Inputs {
+			err := p.validateInterpolation(t.Name, i.Key, i.Value, graph, dependencies, variables)
+			if err != nil {
+				return err
+			}
+		}
+
+		if t.When != "" {
+			err := p.validateInterpolation(t.Name, "when", t.When, graph, dependencies, variables)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *pipelineCtx) validateInterpolation(taskName string, key string, value string, graph map[string][]string, dependencies map[string]bool, variables map[string]bool) error {
+	template, err := expression.ParseTemplate(value)
+	if err != nil {
+		return newInterpolationError(fmt.Errorf("input %s: %w", key, err))
+	}
+
+	for _, ref := range template.References() {
+		if ref.Origin == task.VarInterpolationOrigin {
+			if len(ref.Segments) == 0 || !variables[ref.Segments[0]] {
+				return newInterpolationError(fmt.Errorf("input %s: column %d: not found variable %s", key, ref.Offset+1, strings.Join(ref.Segments, ".")))
+			}
+
+			continue
+		}
+
+		if ref.Origin == task.EachInterpolationOrigin {
+			if _, ok := p.forEachItems[taskName]; !ok {
+				return newInterpolationError(fmt.Errorf("input %s: column %d: origin each is only valid in tasks with forEach", key, ref.Offset+1))
+			}
+
+			if len(ref.Segments) != 1 || (ref.Segments[0] != "key" && ref.Segments[0] != "value") {
+				return newInterpolationError(fmt.Errorf("input %s: column %d: malformed reference, expected each.key or each.value", key, ref.Offset+1))
+			}
+
+			continue
+		}
+
+		if !isValidInterpolationOrigin(ref.Origin) {
+			return newInterpolationError(fmt.Errorf("input %s: column %d: invalid origin %s", key, ref.Offset+1, ref.Origin))
+		}
+
+		if len(ref.Segments) < 2 {
+			return newInterpolationError(fmt.Errorf("input %s: column %d: malformed reference, expected %s.<name>.<attr>", key, ref.Offset+1, ref.Origin))
+		}
+
+		name := ref.Segments[0]
+		if ref.Origin == task.TaskOutputInterpolationOrigin && !isValidTaskOutputName(name) {
+			return newInterpolationError(fmt.Errorf("input %s: column %d: invalid task output %s, expected <name> or <namespace>/<name>", key, ref.Offset+1, name))
+		}
+
+		if ref.Origin == task.ThisInterpolationOrigin {
+			if _, ok := graph[name]; !ok {
+				return newInterpolationError(fmt.Errorf("input %s: column %d: invalid name %s in origin this", key, ref.Offset+1, name))
+			}
+
+			if !dependencies[name] {
+				return newInterpolationError(fmt.Errorf("task %s must depend on task %s to use it in input %s", taskName, name, key))
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateInputTypes checks the type of the inputs, values without expressions
+// are encoded to fail before any task is executed.
+func (p *pipelineCtx) validateInputTypes(infra commonv1alpha1.Infra) error {
+	for _, t := range infra.Spec.Tasks {
+		for _, i := range t.Inputs {
+			switch i.Type {
+			case "", task.StringInputType, task.NumberInputType, task.BoolInputType, task.ListInputType, task.MapInputType, task.JSONInputType:
+			default:
+				return newInvalidInputTypeError(fmt.Errorf("invalid type %s of input %s in task %s", i.Type, i.Key, t.Name))
+			}
+
+			if strings.Contains(i.Value, "{{") {
+				continue
 			}
+
+			_, err := terraform.EncodeInputValue(i)
+			if err != nil {
+				return newInvalidInputTypeError(fmt.Errorf("task %s: %w", t.Name, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func newInvalidInputTypeError(err error) error {
+	return customerror.NewByErr(err, "INVALID_TASK_INPUT_TYPE", fmt.Sprintf(
+		"Use %s, %s, %s, %s, %s or %s as input type with a value of that type",
+		task.StringInputType, task.NumberInputType, task.BoolInputType, task.ListInputType, task.MapInputType, task.JSONInputType,
+	))
+}
+
+func (p *pipelineCtx) validateExecutionConfig(infra commonv1alpha1.Infra) error {
+	if infra.Spec.FailurePolicy != "" && infra.Spec.FailurePolicy != FailFastFailurePolicy && infra.Spec.FailurePolicy != ContinueIndependentFailurePolicy {
+		return customerror.New(
+			fmt.Sprintf("invalid failure policy %s", infra.Spec.FailurePolicy),
+			"INVALID_FAILURE_POLICY",
+			fmt.Sprintf("Use %s or %s as failure policy", FailFastFailurePolicy, ContinueIndependentFailurePolicy),
+		)
+	}
+
+	if infra.Spec.MaxParallelTasks < 0 {
+		return customerror.New(
+			fmt.Sprintf("invalid max parallel tasks %d", infra.Spec.MaxParallelTasks),
+			"INVALID_MAX_PARALLEL_TASKS",
+			"Use a positive number of parallel tasks or leave it empty to use the runner default",
+		)
+	}
+
+	_, err := getExecutionTimeout(infra)
+	if err != nil {
+		return customerror.NewByErr(err, "INVALID_EXECUTION_TIMEOUT", "Verify that the execution timeout is a valid duration, e.g. 10m")
+	}
+
+	for _, t := range infra.Spec.Tasks {
+		_, cancel, err := getTaskContext(context.Background(), t)
+		if err != nil {
+			e := getInvalidTaskTimeoutError(err)
+			return customerror.New(e.Message, e.Code, e.Tip)
+		}
+		cancel()
+
+		_, err = newRetryPolicy(t)
+		if err != nil {
+			e := getInvalidRetryPolicyError(err)
+			return customerror.New(e.Message, e.Code, e.Tip)
+		}
+
+		if !isValidDeletionPolicy(t.DeletionPolicy) {
+			return customerror.New(
+				fmt.Sprintf("invalid deletion policy %s of task %s", t.DeletionPolicy, t.Name),
+				"INVALID_DELETION_POLICY",
+				fmt.Sprintf("Use %s or %s as deletion policy", DeleteDeletionPolicy, RetainDeletionPolicy),
+			)
+		}
+
+		err = validateTaskHooks(t)
+		if err != nil {
+			return customerror.NewByErr(err, "INVALID_TASK_HOOK", "Verify that the hooks have a name, a command or a script and a valid timeout")
 		}
 	}
 
 	return nil
 }
 
-func (p pipelineCtx) validateInfra(infra commonv1alpha1.Infra) error {
-	err := p.validateDependencies(infra)
+func (p *pipelineCtx) validateInfra(infra commonv1alpha1.Infra) error {
+	err := p.validateTaskNames(infra)
+	if err != nil {
+		return err
+	}
+
+	err = p.validateForEachKeys(infra)
+	if err != nil {
+		return err
+	}
+
+	err = p.validateDependencies(infra)
+	if err != nil {
+		return err
+	}
+
+	err = p.validateCycles(infra)
+	if err != nil {
+		return err
+	}
+
+	err = p.validateVariables(infra)
 	if err != nil {
 		return err
 	}
@@ -72,5 +309,51 @@ func (p pipelineCtx) validateInfra(infra commonv1alpha1.Infra) error {
 		return err
 	}
 
-	return err
+	err = p.validateInputTypes(infra)
+	if err != nil {
+		return err
+	}
+
+	return p.validateExecutionConfig(infra)
+}
+
+func isValidInterpolationOrigin(origin string) bool {
+	switch origin {
+	case task.ThisInterpolationOrigin, task.TaskOutputInterpolationOrigin, task.SecretInterpolationOrigin, task.ConfigMapInterpolationOrigin:
+		return true
+	default:
+		return false
+	}
+}
+
+func isValidTaskOutputName(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if part == "" {
+			return false
+		}
+	}
+
+	return strings.Count(name, "/") <= 1
+}
+
+func newInterpolationError(err error) error {
+	return customerror.NewByErr(err, "INVALID_INPUT_INTERPOLATION", "Verify that the task inputs are valid")
+}
+
+// getTransitiveDependencies returns every node that the given node depends on directly or indirectly.
+func getTransitiveDependencies(graph map[string][]string, node string) map[string]bool {
+	dependencies := map[string]bool{}
+	queue := append([]string{}, graph[node]...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if dependencies[current] {
+			continue
+		}
+
+		dependencies[current] = true
+		queue = append(queue, graph[current]...)
+	}
+
+	return dependencies
 }
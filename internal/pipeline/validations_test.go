@@ -0,0 +1,213 @@
+package pipeline
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/customerror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type ValidationsTestSuite struct {
+	suite.Suite
+	pipeline *pipelineCtx
+}
+
+func (suite *ValidationsTestSuite) SetupTest() {
+	logger, _ := zap.NewDevelopment()
+	suite.pipeline = &pipelineCtx{logger: logger, executionContext: make(ExecutionContext)}
+}
+
+func newTestInfra(tasks ...commonv1alpha1.InfraTask) commonv1alpha1.Infra {
+	infra := commonv1alpha1.Infra{}
+	infra.Spec.Tasks = tasks
+	return infra
+}
+
+func (suite *ValidationsTestSuite) TestValidInfra() {
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc"},
+		commonv1alpha1.InfraTask{Name: "subnet", Depends: []string{"vpc"}},
+		commonv1alpha1.InfraTask{
+			Name:    "cluster",
+			Depends: []string{"subnet"},
+			Inputs:  []commonv1alpha1.InfraTaskInput{{Key: "vpc_id", Value: "{{ this.vpc.id }}"}},
+		},
+	)
+
+	assert.NoError(suite.T(), suite.pipeline.validateInfra(infra))
+}
+
+func (suite *ValidationsTestSuite) TestCycle() {
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc"},
+		commonv1alpha1.InfraTask{Name: "a", Depends: []string{"vpc", "c"}},
+		commonv1alpha1.InfraTask{Name: "b", Depends: []string{"a"}},
+		commonv1alpha1.InfraTask{Name: "c", Depends: []string{"b"}},
+	)
+
+	err := customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "TASK_DEPENDENCY_CYCLE", err.Code)
+	assert.Equal(suite.T(), "found a dependency cycle between tasks: a -> c -> b -> a", err.Message)
+}
+
+func (suite *ValidationsTestSuite) TestSelfDependency() {
+	infra := newTestInfra(commonv1alpha1.InfraTask{Name: "a", Depends: []string{"a"}})
+
+	err := customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "TASK_DEPENDENCY_CYCLE", err.Code)
+	assert.Equal(suite.T(), "found a dependency cycle between tasks: a -> a", err.Message)
+}
+
+func (suite *ValidationsTestSuite) TestInterpolationWithoutDependency() {
+	infra := newTestInfra(
+		commonv1alpha1.InfraTask{Name: "vpc"},
+		commonv1alpha1.InfraTask{
+			Name:   "subnet",
+			Inputs: []commonv1alpha1.InfraTaskInput{{Key: "vpc_id", Value: "{{ this.vpc.id }}"}},
+		},
+	)
+
+	err := customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_INPUT_INTERPOLATION", err.Code)
+}
+
+func (suite *ValidationsTestSuite) TestForEachKeys() {
+	for _, key := range []string{"Bank", "bank_1", "-bank", "bank.global", "bank global"} {
+		infra, _, err := suite.pipeline.expandTasks(newTestInfra(commonv1alpha1.InfraTask{
+			Name:    "sns",
+			ForEach: []commonv1alpha1.InfraTaskForEachItem{{Key: key, Value: "topic"}},
+		}))
+		assert.NoError(suite.T(), err)
+
+		assert.Equal(suite.T(), "INVALID_TASK_FOR_EACH", customerror.Unwrap(suite.pipeline.validateInfra(infra)).Code, key)
+	}
+
+	infra, _, err := suite.pipeline.expandTasks(newTestInfra(commonv1alpha1.InfraTask{
+		Name:    "sns",
+		ForEach: []commonv1alpha1.InfraTaskForEachItem{{Value: "bank-1"}},
+	}))
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.pipeline.validateInfra(infra))
+
+	infra = newTestInfra(commonv1alpha1.InfraTask{
+		Name:   "sns",
+		Inputs: []commonv1alpha1.InfraTaskInput{{Key: "name", Value: "{{ each.value }}"}},
+	})
+	assert.Equal(suite.T(), "INVALID_INPUT_INTERPOLATION", customerror.Unwrap(suite.pipeline.validateInfra(infra)).Code)
+}
+
+func (suite *ValidationsTestSuite) TestUnknownDependency() {
+	infra := newTestInfra(commonv1alpha1.InfraTask{Name: "a", Depends: []string{"b"}})
+
+	err := customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_TASK_DEPENDENCY", err.Code)
+}
+
+func (suite *ValidationsTestSuite) TestVariables() {
+	infra := newTestInfra(commonv1alpha1.InfraTask{
+		Name:   "vpc",
+		Inputs: []commonv1alpha1.InfraTaskInput{{Key: "name", Value: "{{ var.env }}-{{ var.region }}"}},
+	})
+	infra.Spec.Variables = []commonv1alpha1.InfraVariable{{Key: "env", Value: "dev"}}
+
+	err := customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_INPUT_INTERPOLATION", err.Code)
+	assert.Equal(suite.T(), "input name: column 18: not found variable region", err.Message)
+
+	infra.Spec.Variables = append(infra.Spec.Variables, commonv1alpha1.InfraVariable{
+		Key:       "region",
+		Value:     "us-east-1",
+		ValueFrom: &commonv1alpha1.InfraVariableValueFrom{ConfigMapKeyRef: &commonv1alpha1.InfraVariableKeyRef{Name: "defaults", Key: "region"}},
+	})
+	err = customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_INFRA_VARIABLE", err.Code)
+
+	infra.Spec.Variables[1].Value = ""
+	assert.NoError(suite.T(), suite.pipeline.validateInfra(infra))
+}
+
+func (suite *ValidationsTestSuite) TestClusterOrigins() {
+	infra := newTestInfra(commonv1alpha1.InfraTask{
+		Name: "db",
+		Inputs: []commonv1alpha1.InfraTaskInput{
+			{Key: "password", Value: "{{ secret.db-credentials.password }}"},
+			{Key: "instance_class", Value: "{{ configmap.db-defaults.instance_class }}"},
+		},
+	})
+	assert.NoError(suite.T(), suite.pipeline.validateInfra(infra))
+
+	infra.Spec.Tasks[0].Inputs[0].Value = "{{ secret.db-credentials }}"
+	err := customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_INPUT_INTERPOLATION", err.Code)
+	assert.Equal(suite.T(), "input password: column 4: malformed reference, expected secret.<name>.<attr>", err.Message)
+}
+
+func (suite *ValidationsTestSuite) TestTaskOutputNamespace() {
+	infra := newTestInfra(commonv1alpha1.InfraTask{
+		Name:   "cluster",
+		Inputs: []commonv1alpha1.InfraTaskInput{{Key: "vpc_id", Value: "{{ task-output.network/vpc.id }}"}},
+	})
+	assert.NoError(suite.T(), suite.pipeline.validateInfra(infra))
+
+	infra.Spec.Tasks[0].Inputs[0].Value = "{{ task-output.network/vpc/main.id }}"
+	err := customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_INPUT_INTERPOLATION", err.Code)
+}
+
+func (suite *ValidationsTestSuite) TestInputTypes() {
+	infra := newTestInfra(commonv1alpha1.InfraTask{
+		Name: "vpc",
+		Inputs: []commonv1alpha1.InfraTaskInput{
+			{Key: "cidr_blocks", Value: `["10.0.0.0/16"]`, Type: "list"},
+			{Key: "tags", Value: `{"env": "dev"}`, Type: "map"},
+			{Key: "azs", Value: "3", Type: "number"},
+			{Key: "nat", Value: "{{ var.nat }}", Type: "bool"},
+		},
+	})
+	infra.Spec.Variables = []commonv1alpha1.InfraVariable{{Key: "nat", Value: "true"}}
+	assert.NoError(suite.T(), suite.pipeline.validateInfra(infra))
+
+	infra.Spec.Tasks[0].Inputs[1].Value = `["dev"]`
+	err := customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_TASK_INPUT_TYPE", err.Code)
+	assert.Equal(suite.T(), `task vpc: input tags must be a JSON object, got "[\"dev\"]"`, err.Message)
+
+	infra.Spec.Tasks[0].Inputs[1].Type = "set"
+	err = customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_TASK_INPUT_TYPE", err.Code)
+}
+
+func (suite *ValidationsTestSuite) TestStructuredInputTypes() {
+	infra := newTestInfra(commonv1alpha1.InfraTask{
+		Name: "vpc",
+		Inputs: []commonv1alpha1.InfraTaskInput{
+			{Key: "network", Value: `{"vpc": {"cidr": "10.0.0.0/16", "tags": {"env": "dev"}}}`, Type: "map"},
+			{Key: "rules", Value: `[{"port": 443, "cidrs": ["0.0.0.0/0"]}, {"port": 22, "cidrs": []}]`, Type: "list"},
+			{Key: "subnets", Value: `[{"az": "us-east-1a", "tags": {"tier": "{{ var.tier }}"}}]`, Type: "list"},
+		},
+	})
+	infra.Spec.Variables = []commonv1alpha1.InfraVariable{{Key: "tier", Value: "private"}}
+	assert.NoError(suite.T(), suite.pipeline.validateInfra(infra))
+
+	infra.Spec.Tasks[0].Inputs[0].Value = `[{"vpc": {"cidr": "10.0.0.0/16"}}]`
+	err := customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_TASK_INPUT_TYPE", err.Code)
+	assert.Equal(suite.T(), `task vpc: input network must be a JSON object, got "[{\"vpc\": {\"cidr\": \"10.0.0.0/16\"}}]"`, err.Message)
+
+	infra.Spec.Tasks[0].Inputs[0].Value = `{"vpc": {"cidr": "10.0.0.0/16"}}`
+	infra.Spec.Tasks[0].Inputs[1].Value = `{"port": 443, "cidrs": ["0.0.0.0/0"]}`
+	err = customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_TASK_INPUT_TYPE", err.Code)
+	assert.Equal(suite.T(), `task vpc: input rules must be a JSON list, got "{\"port\": 443, \"cidrs\": [\"0.0.0.0/0\"]}"`, err.Message)
+
+	infra.Spec.Tasks[0].Inputs[1].Value = `[{"port": 443, "cidrs": ["0.0.0.0/0"]]`
+	err = customerror.Unwrap(suite.pipeline.validateInfra(infra))
+	assert.Equal(suite.T(), "INVALID_TASK_INPUT_TYPE", err.Code)
+}
+
+func TestValidationsTestSuite(t *testing.T) {
+	suite.Run(t, new(ValidationsTestSuite))
+}
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"fmt"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/customerror"
+	"github.com/octopipe/cloudx/internal/variable"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// resolveVariables returns the variables of the infra by key, the values from
+// secrets and config maps are read by the controller.
+func (p *pipelineCtx) resolveVariables(currentInfra commonv1alpha1.Infra) (map[string]ExecutionOutputItem, error) {
+	variables := currentInfra.Spec.Variables
+	if hasVariablesFrom(variables) {
+		variables = []commonv1alpha1.InfraVariable{}
+		err := p.rpcClient.Call("VariableRPCHandler.ResolveVariables", variable.RPCResolveVariablesArgs{
+			InfraRef: types.NamespacedName{Name: currentInfra.Name, Namespace: currentInfra.Namespace},
+		}, &variables)
+		if err != nil {
+			return nil, customerror.NewByErr(err, "INFRA_VARIABLE_ERROR", "Verify that the secrets and config maps used by the variables exist in the infra namespace")
+		}
+	}
+
+	resolved := map[string]ExecutionOutputItem{}
+	for _, v := range variables {
+		resolved[v.Key] = ExecutionOutputItem{Value: v.Value, Sensitive: v.Sensitive}
+	}
+
+	return resolved, nil
+}
+
+func hasVariablesFrom(variables []commonv1alpha1.InfraVariable) bool {
+	for _, v := range variables {
+		if v.ValueFrom != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *pipelineCtx) validateVariables(currentInfra commonv1alpha1.Infra) error {
+	keys := map[string]bool{}
+	for _, v := range currentInfra.Spec.Variables {
+		if v.Key == "" {
+			return newInvalidVariableError(fmt.Errorf("found a variable without key"))
+		}
+
+		if keys[v.Key] {
+			return newInvalidVariableError(fmt.Errorf("duplicated variable %s", v.Key))
+		}
+		keys[v.Key] = true
+
+		if v.ValueFrom == nil {
+			continue
+		}
+
+		if v.Value != "" {
+			return newInvalidVariableError(fmt.Errorf("variable %s can't have value and valueFrom", v.Key))
+		}
+
+		if (v.ValueFrom.SecretKeyRef == nil) == (v.ValueFrom.ConfigMapKeyRef == nil) {
+			return newInvalidVariableError(fmt.Errorf("valueFrom of variable %s must have either a secretKeyRef or a configMapKeyRef", v.Key))
+		}
+	}
+
+	return nil
+}
+
+func newInvalidVariableError(err error) error {
+	return customerror.NewByErr(err, "INVALID_INFRA_VARIABLE", "Verify that the variables have unique keys and a value or a valueFrom")
+}
@@ -11,8 +11,8 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/gitauth"
 	"github.com/octopipe/cloudx/internal/pagination"
 	"github.com/octopipe/cloudx/internal/secret"
 	"go.uber.org/zap"
@@ -155,11 +155,7 @@ func (u useCase) syncRemote(ctx context.Context, dir string, currRepository Repo
 			return err
 		}
 
-		// TODO: SSH AUTHENTICATION
-		cloneOptions.Auth = &http.BasicAuth{
-			Username: string(secret.Data["username"]),
-			Password: string(secret.Data["password"]),
-		}
+		cloneOptions.Auth = gitauth.NewAuthMethod(secret.Data)
 	}
 
 	gitRepository, err := git.PlainClone(dir, false, &cloneOptions)
@@ -0,0 +1,131 @@
+package statearchive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/annotation"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	StateKey          = "terraform.tfstate"
+	DependencyLockKey = ".terraform.lock.hcl"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+type StateArchiveRPCHandler struct {
+	logger    *zap.Logger
+	k8sClient client.Client
+}
+
+func NewStateArchiveRPCHandler(logger *zap.Logger, k8sClient client.Client) *StateArchiveRPCHandler {
+	return &StateArchiveRPCHandler{
+		logger:    logger,
+		k8sClient: k8sClient,
+	}
+}
+
+type RPCArchiveStateArgs struct {
+	InfraRef commonv1alpha1.Ref
+	TaskName string
+	Task     commonv1alpha1.TaskStatus
+}
+
+// ArchiveState keeps the terraform state of a task released from cloudx in a
+// secret of the infra namespace, labeled with the infra and the task so it can
+// be found after the infra is deleted. The secret name is derived from the
+// fingerprint and the state of the task, so retried executions reuse the
+// archive created by the first attempt. The infra is read from the cluster and
+// only its own tasks are archived, in its namespace.
+func (h *StateArchiveRPCHandler) ArchiveState(args *RPCArchiveStateArgs, reply *commonv1alpha1.Ref) error {
+	h.logger.Info("received call", zap.String("method", "StateArchiveRPCHandler.ArchiveState"), zap.String("infra", args.InfraRef.Name), zap.String("task", args.TaskName))
+	infra := commonv1alpha1.Infra{}
+	err := h.k8sClient.Get(context.Background(), types.NamespacedName{Name: args.InfraRef.Name, Namespace: args.InfraRef.Namespace}, &infra)
+	if err != nil {
+		h.logger.Error("failed to get infra", zap.String("infra", args.InfraRef.Name), zap.Error(err))
+		return fmt.Errorf("failed to get the infra %s/%s: %w", args.InfraRef.Namespace, args.InfraRef.Name, err)
+	}
+
+	if !hasTask(infra, args.TaskName) {
+		return fmt.Errorf("task %s doesn't belong to infra %s/%s", args.TaskName, infra.Namespace, infra.Name)
+	}
+
+	secret := v1.Secret{
+		Data: map[string][]byte{
+			StateKey:          []byte(args.Task.State),
+			DependencyLockKey: []byte(args.Task.DependencyLock),
+		},
+	}
+	secret.SetName(getArchiveName(infra.Name, args.TaskName, args.Task))
+	secret.SetNamespace(infra.Namespace)
+	secret.SetLabels(map[string]string{
+		annotation.InfraLabel: getLabelValue(infra.Name),
+		annotation.TaskLabel:  getLabelValue(args.TaskName),
+	})
+	secret.SetAnnotations(map[string]string{
+		annotation.ManagedByAnnotation: "cloudx",
+		annotation.SourceAnnotation:    fmt.Sprintf("%s@%s", args.Task.Source, args.Task.Version),
+	})
+
+	err = h.k8sClient.Create(context.Background(), &secret)
+	if k8serrors.IsAlreadyExists(err) {
+		h.logger.Info("state already archived", zap.String("task", args.TaskName), zap.String("secret", secret.Name))
+		err = h.k8sClient.Get(context.Background(), types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, &secret)
+	}
+
+	if err != nil {
+		h.logger.Error("failed to archive state", zap.String("task", args.TaskName), zap.Error(err))
+		return err
+	}
+
+	*reply = commonv1alpha1.Ref{Name: secret.Name, Namespace: secret.Namespace}
+	return nil
+}
+
+// hasTask checks if the task is in the spec or in the last execution of the
+// infra, removed tasks are only found in the last execution.
+func hasTask(infra commonv1alpha1.Infra, taskName string) bool {
+	for _, t := range infra.Spec.Tasks {
+		if t.Name == taskName {
+			return true
+		}
+	}
+
+	for _, t := range infra.Status.LastExecution.Tasks {
+		if t.Name == taskName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getArchiveName(infraName string, taskName string, task commonv1alpha1.TaskStatus) string {
+	prefix := invalidNameChars.ReplaceAllString(strings.ToLower(fmt.Sprintf("%s-%s", infraName, taskName)), "-")
+	if len(prefix) > 200 {
+		prefix = prefix[:200]
+	}
+
+	sum := sha256.Sum256([]byte(task.Fingerprint + "\n" + task.State))
+	return strings.Trim(prefix, "-") + "-state-" + hex.EncodeToString(sum[:])[:10]
+}
+
+func getLabelValue(value string) string {
+	value = invalidNameChars.ReplaceAllString(strings.ToLower(value), "-")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+
+	return strings.Trim(value, "-")
+}
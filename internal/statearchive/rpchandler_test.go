@@ -0,0 +1,69 @@
+package statearchive
+
+import (
+	"context"
+	"testing"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/annotation"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestArchiveState(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, commonv1alpha1.AddToScheme(scheme))
+	assert.NoError(t, v1.AddToScheme(scheme))
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&commonv1alpha1.Infra{
+			ObjectMeta: metav1.ObjectMeta{Name: "Shared_Infra", Namespace: "team-a"},
+			Status: commonv1alpha1.InfraStatus{LastExecution: commonv1alpha1.ExecutionStatus{
+				Tasks: []commonv1alpha1.TaskExecutionStatus{{Name: "db"}},
+			}},
+		},
+	).Build()
+	handler := NewStateArchiveRPCHandler(zap.NewNop(), k8sClient)
+	args := &RPCArchiveStateArgs{
+		InfraRef: commonv1alpha1.Ref{Name: "Shared_Infra", Namespace: "team-a"},
+		TaskName: "db",
+		Task:     commonv1alpha1.TaskStatus{State: "db-state", DependencyLock: "db-lock", Fingerprint: "f1"},
+	}
+
+	ref := commonv1alpha1.Ref{}
+	assert.NoError(t, handler.ArchiveState(args, &ref))
+	assert.Regexp(t, `^shared-infra-db-state-[0-9a-f]{10}$`, ref.Name)
+	assert.Equal(t, "team-a", ref.Namespace)
+
+	// retried executions reuse the archive of the first attempt
+	retried := commonv1alpha1.Ref{}
+	assert.NoError(t, handler.ArchiveState(args, &retried))
+	assert.Equal(t, ref, retried)
+
+	secrets := v1.SecretList{}
+	assert.NoError(t, k8sClient.List(context.Background(), &secrets, client.InNamespace("team-a")))
+	assert.Len(t, secrets.Items, 1)
+	assert.Equal(t, "db-state", string(secrets.Items[0].Data[StateKey]))
+	assert.Equal(t, "shared-infra", secrets.Items[0].Labels[annotation.InfraLabel])
+
+	// a new state of the task is archived in another secret
+	args.Task.State = "db-state-2"
+	other := commonv1alpha1.Ref{}
+	assert.NoError(t, handler.ArchiveState(args, &other))
+	assert.NotEqual(t, ref.Name, other.Name)
+
+	// only tasks of an existing infra are archived
+	args.TaskName = "cache"
+	err := handler.ArchiveState(args, &other)
+	assert.EqualError(t, err, "task cache doesn't belong to infra team-a/Shared_Infra")
+
+	args.TaskName = "db"
+	args.InfraRef.Namespace = "team-b"
+	err = handler.ArchiveState(args, &other)
+	assert.ErrorContains(t, err, "failed to get the infra team-b/Shared_Infra")
+}
@@ -9,6 +9,20 @@ const (
 const (
 	ThisInterpolationOrigin       = "this"
 	TaskOutputInterpolationOrigin = "task-output"
+	VarInterpolationOrigin        = "var"
+	SecretInterpolationOrigin     = "secret"
+	ConfigMapInterpolationOrigin  = "configmap"
+	EachInterpolationOrigin       = "each"
+)
+
+// Types of the task inputs, list, map and json values are written as JSON
+const (
+	StringInputType = "string"
+	NumberInputType = "number"
+	BoolInputType   = "bool"
+	ListInputType   = "list"
+	MapInputType    = "map"
+	JSONInputType   = "json"
 )
 
 type TaskInput struct {
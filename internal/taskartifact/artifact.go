@@ -0,0 +1,125 @@
+package taskartifact
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// ConfigMediaType identifies a task bundle published by cloudx
+	ConfigMediaType types.MediaType = "application/vnd.cloudx.task.config.v1+json"
+	// LayerMediaType is the layer with the files of the task module
+	LayerMediaType types.MediaType = "application/vnd.cloudx.task.content.v1.tar+gzip"
+)
+
+// NewImage packs the files of a task module, keyed by their slash separated
+// path relative to the module root, in an OCI artifact with a single layer.
+func NewImage(files map[string][]byte) (v1.Image, error) {
+	paths := []string{}
+	for p := range files {
+		err := ValidatePath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, p := range paths {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     p,
+			Mode:     0644,
+			Size:     int64(len(files[p])),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tw.Write(files[p])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := tw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	}, tarball.WithMediaType(LayerMediaType))
+	if err != nil {
+		return nil, err
+	}
+
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, ConfigMediaType)
+	return mutate.AppendLayers(img, layer)
+}
+
+// GetContentLayers returns the layers with the files of a task module, images
+// published before the cloudx media types have all their layers used.
+func GetContentLayers(img v1.Image) ([]v1.Layer, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Config.MediaType != ConfigMediaType {
+		return layers, nil
+	}
+
+	contentLayers := []v1.Layer{}
+	for _, l := range layers {
+		mediaType, err := l.MediaType()
+		if err != nil {
+			return nil, err
+		}
+
+		if mediaType == LayerMediaType {
+			contentLayers = append(contentLayers, l)
+		}
+	}
+
+	if len(contentLayers) == 0 {
+		return nil, fmt.Errorf("not found layer of media type %s in the task artifact", LayerMediaType)
+	}
+
+	return contentLayers, nil
+}
+
+// ValidatePath rejects paths of files that would be written outside the
+// module root, e.g. /etc/passwd or ../main.tf.
+func ValidatePath(p string) error {
+	if p == "" || strings.HasPrefix(p, "/") || strings.Contains(p, "\\") {
+		return fmt.Errorf("invalid task file path %s", p)
+	}
+
+	cleaned := path.Clean(p)
+	if cleaned != p || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Errorf("invalid task file path %s", p)
+	}
+
+	return nil
+}
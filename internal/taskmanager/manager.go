@@ -5,10 +5,13 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/octopipe/cloudx/internal/taskartifact"
 	"go.uber.org/zap"
 )
 
 type Manager interface {
+	// Publish pushes the files of a task module, keyed by their slash
+	// separated path relative to the module root, as a task artifact
 	Publish(taskName string, filecontents map[string][]byte) error
 }
 
@@ -28,7 +31,7 @@ func (m manager) Publish(taskName string, filecontents map[string][]byte) error
 		return err
 	}
 
-	newImage, err := crane.Image(filecontents)
+	newImage, err := taskartifact.NewImage(filecontents)
 	if err != nil {
 		return err
 	}
@@ -2,10 +2,14 @@ package taskoutput
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/annotation"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
@@ -28,17 +32,85 @@ func NewTaskOutputRPCHandler(logger *zap.Logger, k8sClient client.Client, taskOu
 
 type RPCGetTaskOutputArgs struct {
 	Ref types.NamespacedName
+	// InfraRef is the infra executed by the runner reading the task output
+	InfraRef types.NamespacedName
 }
 
+// GetTaskOutput reads a task output for the runner of an infra, the requester
+// namespace is the namespace of the infra found by the controller.
 func (h *TaskOutputRPCHandler) GetTaskOutput(args *RPCGetTaskOutputArgs, reply *commonv1alpha1.TaskOutput) error {
+	requester := commonv1alpha1.Infra{}
+	err := h.k8sClient.Get(context.Background(), args.InfraRef, &requester)
+	if err != nil {
+		return fmt.Errorf("failed to get the infra %s reading the task output: %w", args.InfraRef.String(), err)
+	}
+
 	currentTaskOutput, err := h.taskOutputRepository.Get(context.Background(), args.Ref.Name, args.Ref.Namespace)
+	if err != nil {
+		return err
+	}
+
+	if !isNamespaceAllowed(currentTaskOutput, requester.Namespace) {
+		h.logger.Info("denied cross namespace task output read", zap.String("taskoutput", args.Ref.String()), zap.String("requester", args.InfraRef.String()))
+		return fmt.Errorf("task output %s is not shared with namespace %s, add it to the %s annotation", args.Ref.String(), requester.Namespace, annotation.AllowedNamespacesAnnotation)
+	}
+
+	err = h.fillSensitiveValues(&currentTaskOutput)
+	if err != nil {
+		return err
+	}
+
 	*reply = currentTaskOutput
-	return err
+	return nil
+}
+
+// fillSensitiveValues reads the values of the sensitive outputs from the
+// secret of the task output, they are not stored in the task output itself.
+func (h *TaskOutputRPCHandler) fillSensitiveValues(taskOutput *commonv1alpha1.TaskOutput) error {
+	if taskOutput.Spec.Secret.Name == "" {
+		return nil
+	}
+
+	secret := v1.Secret{}
+	err := h.k8sClient.Get(context.Background(), types.NamespacedName{
+		Name:      taskOutput.Spec.Secret.Name,
+		Namespace: taskOutput.Spec.Secret.Namespace,
+	}, &secret)
+	if err != nil {
+		return err
+	}
+
+	for i, out := range taskOutput.Spec.Outputs {
+		if value, ok := secret.Data[out.Key]; ok && out.Sensitive {
+			taskOutput.Spec.Outputs[i].Value = &apiextensionsv1.JSON{Raw: value}
+		}
+	}
+
+	return nil
 }
 
+// isNamespaceAllowed checks the allow-list of a task output, task outputs are
+// always readable from their own namespace.
+func isNamespaceAllowed(taskOutput commonv1alpha1.TaskOutput, namespace string) bool {
+	if taskOutput.Namespace == namespace {
+		return true
+	}
+
+	for _, allowed := range strings.Split(taskOutput.GetAnnotations()[annotation.AllowedNamespacesAnnotation], ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RPCCreateTaskOutputItem is a terraform output, Value and Type are JSON.
 type RPCCreateTaskOutputItem struct {
 	commonv1alpha1.InfraTaskOutputItem
 	Value string
+	Type  string
 }
 
 type RPCCreateTaskOutputArgs struct {
@@ -64,7 +136,7 @@ func (h TaskOutputRPCHandler) isSecretTaskOutput(items []RPCCreateTaskOutputItem
 }
 
 func (h *TaskOutputRPCHandler) applySecret(args *RPCCreateTaskOutputArgs) (types.NamespacedName, error) {
-	newSecret := v1.Secret{}
+	newSecret := v1.Secret{Data: map[string][]byte{}}
 	newSecret.SetName(args.Name)
 	newSecret.SetNamespace(args.Namespace)
 
@@ -124,8 +196,12 @@ func (h *TaskOutputRPCHandler) ApplyTaskOuput(args *RPCCreateTaskOutputArgs, rep
 			Sensitive: item.Sensitive,
 		}
 
+		if item.Type != "" {
+			newOutput.Type = &apiextensionsv1.JSON{Raw: []byte(item.Type)}
+		}
+
 		if !item.Sensitive {
-			newOutput.Value = item.Value
+			newOutput.Value = &apiextensionsv1.JSON{Raw: []byte(item.Value)}
 		}
 
 		newTaskOutput.Spec.Outputs = append(newTaskOutput.Spec.Outputs, newOutput)
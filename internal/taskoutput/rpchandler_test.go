@@ -0,0 +1,49 @@
+package taskoutput
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/annotation"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetTaskOutput(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, commonv1alpha1.AddToScheme(scheme))
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&commonv1alpha1.Infra{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"}},
+		&commonv1alpha1.Infra{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-b"}},
+		&commonv1alpha1.TaskOutput{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "vpc",
+				Namespace:   "network",
+				Annotations: map[string]string{annotation.AllowedNamespacesAnnotation: "team-a, team-c"},
+			},
+			Spec: commonv1alpha1.TaskOutputSpec{Outputs: []commonv1alpha1.TaskOutputSpecItem{
+				{Key: "id", Value: &apiextensionsv1.JSON{Raw: []byte(`"vpc-1"`)}},
+			}},
+		},
+	).Build()
+	handler := NewTaskOutputRPCHandler(zap.NewNop(), k8sClient, NewK8sRepository(k8sClient))
+	ref := types.NamespacedName{Name: "vpc", Namespace: "network"}
+
+	taskOutput := commonv1alpha1.TaskOutput{}
+	err := handler.GetTaskOutput(&RPCGetTaskOutputArgs{Ref: ref, InfraRef: types.NamespacedName{Name: "app", Namespace: "team-a"}}, &taskOutput)
+	assert.NoError(t, err)
+	assert.Equal(t, `"vpc-1"`, string(taskOutput.Spec.Outputs[0].Value.Raw))
+
+	err = handler.GetTaskOutput(&RPCGetTaskOutputArgs{Ref: ref, InfraRef: types.NamespacedName{Name: "app", Namespace: "team-b"}}, &commonv1alpha1.TaskOutput{})
+	assert.ErrorContains(t, err, "is not shared with namespace team-b")
+
+	// allowed namespaces without an infra can't be claimed by the runner
+	err = handler.GetTaskOutput(&RPCGetTaskOutputArgs{Ref: ref, InfraRef: types.NamespacedName{Name: "app", Namespace: "team-c"}}, &commonv1alpha1.TaskOutput{})
+	assert.ErrorContains(t, err, "failed to get the infra team-c/app")
+}
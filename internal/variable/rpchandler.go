@@ -0,0 +1,239 @@
+package variable
+
+import (
+	"context"
+	"fmt"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/octopipe/cloudx/internal/expression"
+	"github.com/octopipe/cloudx/internal/task"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type VariableRPCHandler struct {
+	logger    *zap.Logger
+	k8sClient client.Client
+}
+
+func NewVariableRPCHandler(logger *zap.Logger, k8sClient client.Client) *VariableRPCHandler {
+	return &VariableRPCHandler{
+		logger:    logger,
+		k8sClient: k8sClient,
+	}
+}
+
+type RPCResolveVariablesArgs struct {
+	// InfraRef is the infra executed by the runner, its own variables are
+	// resolved in its namespace
+	InfraRef types.NamespacedName
+}
+
+// getInfra gets the infra executed by the runner calling the handler, secrets
+// and config maps are only read when the infra declares them, in its namespace.
+func (h *VariableRPCHandler) getInfra(ref types.NamespacedName) (commonv1alpha1.Infra, error) {
+	infra := commonv1alpha1.Infra{}
+	err := h.k8sClient.Get(context.Background(), ref, &infra)
+	if err != nil {
+		return commonv1alpha1.Infra{}, fmt.Errorf("failed to get the infra %s: %w", ref.String(), err)
+	}
+
+	return infra, nil
+}
+
+// ResolveVariables reads the valueFrom of the variables of the infra from the
+// secrets and config maps of its namespace. Values read from secrets are
+// always sensitive.
+func (h *VariableRPCHandler) ResolveVariables(args *RPCResolveVariablesArgs, reply *[]commonv1alpha1.InfraVariable) error {
+	h.logger.Info("received call", zap.String("method", "VariableRPCHandler.ResolveVariables"), zap.String("infra", args.InfraRef.String()))
+	infra, err := h.getInfra(args.InfraRef)
+	if err != nil {
+		return err
+	}
+
+	variables := []commonv1alpha1.InfraVariable{}
+	for _, v := range infra.Spec.Variables {
+		variable := commonv1alpha1.InfraVariable{Key: v.Key, Value: v.Value, Sensitive: v.Sensitive}
+		if v.ValueFrom != nil {
+			value, err := h.getValueFrom(infra.Namespace, *v.ValueFrom)
+			if err != nil {
+				h.logger.Error("failed to resolve variable", zap.String("variable", v.Key), zap.Error(err))
+				return fmt.Errorf("variable %s: %w", v.Key, err)
+			}
+
+			variable.Value = value
+			variable.Sensitive = v.Sensitive || v.ValueFrom.SecretKeyRef != nil
+		}
+
+		variables = append(variables, variable)
+	}
+
+	*reply = variables
+	return nil
+}
+
+type RPCGetKeyArgs struct {
+	// InfraRef is the infra executed by the runner
+	InfraRef types.NamespacedName
+	Name     string
+	Key      string
+}
+
+type RPCGetKeyReply struct {
+	Value string
+}
+
+// GetSecretKey returns the value of a key of a secret, it's used by the
+// secret interpolation origin.
+func (h *VariableRPCHandler) GetSecretKey(args *RPCGetKeyArgs, reply *RPCGetKeyReply) error {
+	h.logger.Info("received call", zap.String("method", "VariableRPCHandler.GetSecretKey"), zap.String("secret", args.Name), zap.String("infra", args.InfraRef.String()))
+	value, err := h.getDeclaredKey(args, task.SecretInterpolationOrigin, commonv1alpha1.InfraVariableValueFrom{
+		SecretKeyRef: &commonv1alpha1.InfraVariableKeyRef{Name: args.Name, Key: args.Key},
+	})
+	if err != nil {
+		h.logger.Error("failed to get secret key", zap.String("secret", args.Name), zap.Error(err))
+		return err
+	}
+
+	reply.Value = value
+	return nil
+}
+
+type RPCGetSecretDataArgs struct {
+	// InfraRef is the infra executed by the runner
+	InfraRef types.NamespacedName
+	Name     string
+}
+
+// GetSecretData returns all the keys of a secret, it's used to read the
+// credentials of private task sources. Only the credentialsRef of the tasks
+// of the infra are returned.
+func (h *VariableRPCHandler) GetSecretData(args *RPCGetSecretDataArgs, reply *map[string][]byte) error {
+	h.logger.Info("received call", zap.String("method", "VariableRPCHandler.GetSecretData"), zap.String("secret", args.Name), zap.String("infra", args.InfraRef.String()))
+	infra, err := h.getInfra(args.InfraRef)
+	if err != nil {
+		return err
+	}
+
+	if !isCredentialsRefOfInfra(infra, args.Name) {
+		return fmt.Errorf("secret %s isn't the credentialsRef of a task of infra %s", args.Name, args.InfraRef.String())
+	}
+
+	secret := v1.Secret{}
+	err = h.k8sClient.Get(context.Background(), types.NamespacedName{Name: args.Name, Namespace: infra.Namespace}, &secret)
+	if err != nil {
+		h.logger.Error("failed to get secret", zap.String("secret", args.Name), zap.Error(err))
+		return err
+	}
+
+	*reply = secret.Data
+	return nil
+}
+
+// GetConfigMapKey returns the value of a key of a config map, it's used by the
+// configmap interpolation origin.
+func (h *VariableRPCHandler) GetConfigMapKey(args *RPCGetKeyArgs, reply *RPCGetKeyReply) error {
+	h.logger.Info("received call", zap.String("method", "VariableRPCHandler.GetConfigMapKey"), zap.String("configmap", args.Name), zap.String("infra", args.InfraRef.String()))
+	value, err := h.getDeclaredKey(args, task.ConfigMapInterpolationOrigin, commonv1alpha1.InfraVariableValueFrom{
+		ConfigMapKeyRef: &commonv1alpha1.InfraVariableKeyRef{Name: args.Name, Key: args.Key},
+	})
+	if err != nil {
+		h.logger.Error("failed to get config map key", zap.String("configmap", args.Name), zap.Error(err))
+		return err
+	}
+
+	reply.Value = value
+	return nil
+}
+
+// getDeclaredKey reads a key referenced by the inputs or conditions of the
+// tasks of the infra, from the infra namespace.
+func (h *VariableRPCHandler) getDeclaredKey(args *RPCGetKeyArgs, origin string, valueFrom commonv1alpha1.InfraVariableValueFrom) (string, error) {
+	infra, err := h.getInfra(args.InfraRef)
+	if err != nil {
+		return "", err
+	}
+
+	if !isReferencedByInfra(infra, origin, args.Name, args.Key) {
+		return "", fmt.Errorf("key %s of %s %s isn't used by the tasks of infra %s", args.Key, origin, args.Name, args.InfraRef.String())
+	}
+
+	return h.getValueFrom(infra.Namespace, valueFrom)
+}
+
+// isReferencedByInfra checks if an input or a condition of a task of the infra
+// uses the key with the given origin.
+func isReferencedByInfra(infra commonv1alpha1.Infra, origin string, name string, key string) bool {
+	for _, t := range infra.Spec.Tasks {
+		values := []string{t.When}
+		for _, i := range t.Inputs {
+			values = append(values, i.Value)
+		}
+
+		for _, value := range values {
+			template, err := expression.ParseTemplate(value)
+			if err != nil {
+				continue
+			}
+
+			for _, ref := range template.References() {
+				if ref.Origin == origin && len(ref.Segments) >= 2 && ref.Segments[0] == name && ref.Segments[1] == key {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// isCredentialsRefOfInfra checks if a task of the infra uses the secret as the
+// credentials of its source, in the infra namespace.
+func isCredentialsRefOfInfra(infra commonv1alpha1.Infra, name string) bool {
+	for _, t := range infra.Spec.Tasks {
+		ref := t.Terraform.CredentialsRef
+		if ref.Name == name && (ref.Namespace == "" || ref.Namespace == infra.Namespace) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *VariableRPCHandler) getValueFrom(namespace string, valueFrom commonv1alpha1.InfraVariableValueFrom) (string, error) {
+	if valueFrom.SecretKeyRef != nil {
+		ref := valueFrom.SecretKeyRef
+		secret := v1.Secret{}
+		err := h.k8sClient.Get(context.Background(), types.NamespacedName{Name: ref.Name, Namespace: namespace}, &secret)
+		if err != nil {
+			return "", err
+		}
+
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("not found key %s in secret %s", ref.Key, ref.Name)
+		}
+
+		return string(value), nil
+	}
+
+	if valueFrom.ConfigMapKeyRef != nil {
+		ref := valueFrom.ConfigMapKeyRef
+		configMap := v1.ConfigMap{}
+		err := h.k8sClient.Get(context.Background(), types.NamespacedName{Name: ref.Name, Namespace: namespace}, &configMap)
+		if err != nil {
+			return "", err
+		}
+
+		value, ok := configMap.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("not found key %s in config map %s", ref.Key, ref.Name)
+		}
+
+		return value, nil
+	}
+
+	return "", fmt.Errorf("valueFrom must have a secretKeyRef or a configMapKeyRef")
+}
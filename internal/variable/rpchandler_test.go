@@ -0,0 +1,116 @@
+package variable
+
+import (
+	"testing"
+
+	commonv1alpha1 "github.com/octopipe/cloudx/apis/common/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestK8sClient(t *testing.T, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, commonv1alpha1.AddToScheme(scheme))
+	assert.NoError(t, v1.AddToScheme(scheme))
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func TestGetKeys(t *testing.T) {
+	k8sClient := newTestK8sClient(t,
+		&commonv1alpha1.Infra{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec: commonv1alpha1.InfraSpec{Tasks: []commonv1alpha1.InfraTask{{
+				Name: "db",
+				When: "{{ configmap.db-defaults.enabled }}",
+				Inputs: []commonv1alpha1.InfraTaskInput{
+					{Key: "password", Value: "{{ secret.db-credentials.password }}"},
+				},
+			}}},
+		},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "team-a"}, Data: map[string][]byte{"password": []byte("a"), "token": []byte("b")}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "team-b"}, Data: map[string][]byte{"password": []byte("c")}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "db-defaults", Namespace: "team-a"}, Data: map[string]string{"enabled": "true"}},
+	)
+	handler := NewVariableRPCHandler(zap.NewNop(), k8sClient)
+	infraRef := types.NamespacedName{Name: "app", Namespace: "team-a"}
+
+	reply := RPCGetKeyReply{}
+	err := handler.GetSecretKey(&RPCGetKeyArgs{InfraRef: infraRef, Name: "db-credentials", Key: "password"}, &reply)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", reply.Value)
+
+	reply = RPCGetKeyReply{}
+	err = handler.GetConfigMapKey(&RPCGetKeyArgs{InfraRef: infraRef, Name: "db-defaults", Key: "enabled"}, &reply)
+	assert.NoError(t, err)
+	assert.Equal(t, "true", reply.Value)
+
+	err = handler.GetSecretKey(&RPCGetKeyArgs{InfraRef: infraRef, Name: "db-credentials", Key: "token"}, &RPCGetKeyReply{})
+	assert.EqualError(t, err, "key token of secret db-credentials isn't used by the tasks of infra team-a/app")
+
+	err = handler.GetConfigMapKey(&RPCGetKeyArgs{InfraRef: infraRef, Name: "db-credentials", Key: "password"}, &RPCGetKeyReply{})
+	assert.EqualError(t, err, "key password of configmap db-credentials isn't used by the tasks of infra team-a/app")
+
+	err = handler.GetSecretKey(&RPCGetKeyArgs{InfraRef: types.NamespacedName{Name: "app", Namespace: "team-b"}, Name: "db-credentials", Key: "password"}, &RPCGetKeyReply{})
+	assert.ErrorContains(t, err, "failed to get the infra team-b/app")
+}
+
+func TestResolveVariables(t *testing.T) {
+	k8sClient := newTestK8sClient(t,
+		&commonv1alpha1.Infra{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec: commonv1alpha1.InfraSpec{Variables: []commonv1alpha1.InfraVariable{
+				{Key: "region", Value: "us-east-1"},
+				{Key: "password", ValueFrom: &commonv1alpha1.InfraVariableValueFrom{
+					SecretKeyRef: &commonv1alpha1.InfraVariableKeyRef{Name: "db-credentials", Key: "password"},
+				}},
+			}},
+		},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "team-a"}, Data: map[string][]byte{"password": []byte("a")}},
+	)
+	handler := NewVariableRPCHandler(zap.NewNop(), k8sClient)
+
+	variables := []commonv1alpha1.InfraVariable{}
+	err := handler.ResolveVariables(&RPCResolveVariablesArgs{InfraRef: types.NamespacedName{Name: "app", Namespace: "team-a"}}, &variables)
+	assert.NoError(t, err)
+	assert.Equal(t, []commonv1alpha1.InfraVariable{
+		{Key: "region", Value: "us-east-1"},
+		{Key: "password", Value: "a", Sensitive: true},
+	}, variables)
+
+	err = handler.ResolveVariables(&RPCResolveVariablesArgs{InfraRef: types.NamespacedName{Name: "app", Namespace: "team-b"}}, &variables)
+	assert.ErrorContains(t, err, "failed to get the infra team-b/app")
+}
+
+func TestGetSecretData(t *testing.T) {
+	k8sClient := newTestK8sClient(t,
+		&commonv1alpha1.Infra{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec: commonv1alpha1.InfraSpec{Tasks: []commonv1alpha1.InfraTask{{
+				Name:      "db",
+				Terraform: commonv1alpha1.Terraform{Source: "https://github.com/org/modules", CredentialsRef: commonv1alpha1.Ref{Name: "git-credentials"}},
+			}}},
+		},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "git-credentials", Namespace: "team-a"}, Data: map[string][]byte{"token": []byte("a")}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "team-a"}, Data: map[string][]byte{"password": []byte("b")}},
+	)
+	handler := NewVariableRPCHandler(zap.NewNop(), k8sClient)
+	infraRef := types.NamespacedName{Name: "app", Namespace: "team-a"}
+
+	data := map[string][]byte{}
+	err := handler.GetSecretData(&RPCGetSecretDataArgs{InfraRef: infraRef, Name: "git-credentials"}, &data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"token": []byte("a")}, data)
+
+	err = handler.GetSecretData(&RPCGetSecretDataArgs{InfraRef: infraRef, Name: "db-credentials"}, &data)
+	assert.EqualError(t, err, "secret db-credentials isn't the credentialsRef of a task of infra team-a/app")
+
+	err = handler.GetSecretData(&RPCGetSecretDataArgs{InfraRef: types.NamespacedName{Name: "app", Namespace: "team-b"}, Name: "git-credentials"}, &data)
+	assert.ErrorContains(t, err, "failed to get the infra team-b/app")
+}